@@ -0,0 +1,336 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-antenna-go/v2/account"
+	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
+	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/util"
+)
+
+// actionsPage is the number of the actions of the account read at a time
+const actionsPage = 100
+
+type (
+	// Client is the subset of the IoTeX API hermes calls, sending actions with its account
+	Client interface {
+		ReceiptReader
+
+		Account() account.Account
+		ReadState(ctx context.Context, request *iotexapi.ReadStateRequest) (*iotexapi.ReadStateResponse, error)
+		GetChainMeta(ctx context.Context) (*iotextypes.ChainMeta, error)
+		GetEpochHeight(ctx context.Context, epoch uint64) (uint64, error)
+		GetLogs(ctx context.Context, filter *iotexapi.LogsFilter, fromHeight uint64, count uint64) ([]*iotextypes.Log, error)
+		GetBalance(ctx context.Context) (*big.Int, error)
+		// GetNonce returns the nonce of the last confirmed action of the account, and the nonce of its next action
+		GetNonce(ctx context.Context) (uint64, uint64, error)
+		// GetActionByNonce returns the mined action of the account with nonce, or nil if there is none
+		GetActionByNonce(ctx context.Context, nonce uint64) (*iotexapi.ActionInfo, error)
+		SuggestGasPrice(ctx context.Context) (*big.Int, error)
+		EstimateExecution(ctx context.Context, contract address.Address, abi abi.ABI, amount *big.Int, method string, args ...interface{}) (uint64, error)
+		EstimateTransfer(ctx context.Context, to address.Address, amount *big.Int) (uint64, error)
+		ReadContract(ctx context.Context, contract address.Address, abi abi.ABI, method string, args ...interface{}) (Data, error)
+		ExecuteContract(ctx context.Context, contract address.Address, abi abi.ABI, amount *big.Int, gas Gas, method string, args ...interface{}) (hash.Hash256, error)
+		Transfer(ctx context.Context, to address.Address, amount *big.Int, gas Gas) (hash.Hash256, error)
+		AddDeposit(ctx context.Context, bucketID uint64, amount *big.Int, gas Gas) (hash.Hash256, error)
+		ClaimReward(ctx context.Context, amount *big.Int, gas Gas) (hash.Hash256, error)
+	}
+
+	// ReceiptReader reads the receipt and the pending state of an action
+	ReceiptReader interface {
+		// GetReceipt returns the receipt of the action, or an error with code NotFound if it is not mined yet
+		GetReceipt(ctx context.Context, h hash.Hash256) (*iotextypes.Receipt, error)
+		// HasAction returns whether the action is either mined or pending in the action pool
+		HasAction(ctx context.Context, h hash.Hash256) (bool, error)
+	}
+
+	// Gas is the gas price and limit of an action, the node suggests the ones which are not set
+	Gas struct {
+		Price *big.Int
+		Limit uint64
+	}
+
+	// Data is the data returned from read contract
+	Data struct {
+		abi    abi.ABI
+		method string
+		Raw    []byte
+	}
+
+	client struct {
+		c iotex.AuthedClient
+	}
+)
+
+// Unmarshal unmarshals data into a data holder object
+func (d Data) Unmarshal(v interface{}) error {
+	return d.abi.Unpack(v, d.method, d.Raw)
+}
+
+// NewClient returns a Client calling the API with the account
+func NewClient(api iotexapi.APIServiceClient, acc account.Account) Client {
+	return &client{c: iotex.NewAuthedClient(api, acc)}
+}
+
+// Connect connects to the endpoint of cfg with the vault account
+func Connect(cfg *config.Config) (Client, *grpc.ClientConn, error) {
+	acc, err := util.GetVaultAccount(cfg.Vault.Password)
+	if err != nil {
+		return nil, nil, err
+	}
+	// verify the account matches the reward address
+	if acc.Address().String() != cfg.Vault.Address {
+		return nil, nil, fmt.Errorf("key and address do not match")
+	}
+	conn, err := iotex.NewDefaultGRPCConn(cfg.Endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewClient(iotexapi.NewAPIServiceClient(conn), acc), conn, nil
+}
+
+func (c *client) Account() account.Account {
+	return c.c.Account()
+}
+
+func (c *client) ReadState(ctx context.Context, request *iotexapi.ReadStateRequest) (*iotexapi.ReadStateResponse, error) {
+	return c.c.API().ReadState(ctx, request)
+}
+
+func (c *client) GetChainMeta(ctx context.Context) (*iotextypes.ChainMeta, error) {
+	resp, err := c.c.API().GetChainMeta(ctx, &iotexapi.GetChainMetaRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ChainMeta, nil
+}
+
+func (c *client) GetEpochHeight(ctx context.Context, epoch uint64) (uint64, error) {
+	resp, err := c.c.API().GetEpochMeta(ctx, &iotexapi.GetEpochMetaRequest{EpochNumber: epoch})
+	if err != nil {
+		return 0, err
+	}
+	return resp.EpochData.Height, nil
+}
+
+func (c *client) GetLogs(ctx context.Context, filter *iotexapi.LogsFilter, fromHeight uint64, count uint64) ([]*iotextypes.Log, error) {
+	resp, err := c.c.API().GetLogs(ctx, &iotexapi.GetLogsRequest{
+		Filter: filter,
+		Lookup: &iotexapi.GetLogsRequest_ByRange{
+			ByRange: &iotexapi.GetLogsByRange{FromBlock: fromHeight, Count: count},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Logs, nil
+}
+
+func (c *client) GetBalance(ctx context.Context) (*big.Int, error) {
+	resp, err := c.c.API().GetAccount(ctx, &iotexapi.GetAccountRequest{Address: c.c.Account().Address().String()})
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := new(big.Int).SetString(resp.AccountMeta.Balance, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid balance %s", resp.AccountMeta.Balance)
+	}
+	return balance, nil
+}
+
+func (c *client) GetNonce(ctx context.Context) (uint64, uint64, error) {
+	resp, err := c.c.API().GetAccount(ctx, &iotexapi.GetAccountRequest{Address: c.c.Account().Address().String()})
+	if err != nil {
+		return 0, 0, err
+	}
+	return resp.AccountMeta.Nonce, resp.AccountMeta.PendingNonce, nil
+}
+
+func (c *client) GetActionByNonce(ctx context.Context, nonce uint64) (*iotexapi.ActionInfo, error) {
+	addr := c.c.Account().Address().String()
+	resp, err := c.c.API().GetAccount(ctx, &iotexapi.GetAccountRequest{Address: addr})
+	if err != nil {
+		return nil, err
+	}
+	// the actions of the account are indexed in the order they are mined, so they are read back from the latest one
+	// until one of a lower nonce
+	for end := resp.AccountMeta.NumActions; end > 0; {
+		start := uint64(0)
+		if end > actionsPage {
+			start = end - actionsPage
+		}
+		actions, err := c.c.API().GetActions(ctx, &iotexapi.GetActionsRequest{
+			Lookup: &iotexapi.GetActionsRequest_ByAddr{
+				ByAddr: &iotexapi.GetActionsByAddressRequest{Address: addr, Start: start, Count: end - start},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := len(actions.ActionInfo) - 1; i >= 0; i-- {
+			info := actions.ActionInfo[i]
+			// the transfers the account receives are indexed too
+			if info.Sender != addr {
+				continue
+			}
+			switch n := info.Action.GetCore().GetNonce(); {
+			case n == nonce:
+				return info, nil
+			case n < nonce:
+				return nil, nil
+			}
+		}
+		end = start
+	}
+	return nil, nil
+}
+
+func (c *client) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	resp, err := c.c.API().SuggestGasPrice(ctx, &iotexapi.SuggestGasPriceRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(resp.GasPrice), nil
+}
+
+func (c *client) EstimateExecution(ctx context.Context, contract address.Address, abi abi.ABI, amount *big.Int, method string, args ...interface{}) (uint64, error) {
+	data, err := abi.Pack(method, args...)
+	if err != nil {
+		return 0, err
+	}
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+	return c.estimate(ctx, &iotexapi.EstimateActionGasConsumptionRequest{
+		Action: &iotexapi.EstimateActionGasConsumptionRequest_Execution{
+			Execution: &iotextypes.Execution{
+				Amount:   amount.String(),
+				Contract: contract.String(),
+				Data:     data,
+			},
+		},
+	})
+}
+
+func (c *client) EstimateTransfer(ctx context.Context, to address.Address, amount *big.Int) (uint64, error) {
+	return c.estimate(ctx, &iotexapi.EstimateActionGasConsumptionRequest{
+		Action: &iotexapi.EstimateActionGasConsumptionRequest_Transfer{
+			Transfer: &iotextypes.Transfer{
+				Amount:    amount.String(),
+				Recipient: to.String(),
+			},
+		},
+	})
+}
+
+// estimate returns the gas the node estimates request to consume when sent by the account
+func (c *client) estimate(ctx context.Context, request *iotexapi.EstimateActionGasConsumptionRequest) (uint64, error) {
+	request.CallerAddress = c.c.Account().Address().String()
+	resp, err := c.c.API().EstimateActionGasConsumption(ctx, request)
+	if err != nil {
+		return 0, err
+	}
+	return resp.Gas, nil
+}
+
+func (c *client) ReadContract(ctx context.Context, contract address.Address, abi abi.ABI, method string, args ...interface{}) (Data, error) {
+	data, err := c.c.Contract(contract, abi).Read(method, args...).Call(ctx)
+	if err != nil {
+		return Data{}, err
+	}
+	return Data{abi: abi, method: method, Raw: data.Raw}, nil
+}
+
+func (c *client) ExecuteContract(ctx context.Context, contract address.Address, abi abi.ABI, amount *big.Int, gas Gas, method string, args ...interface{}) (hash.Hash256, error) {
+	caller := c.c.Contract(contract, abi).Execute(method, args...)
+	if amount != nil {
+		caller = caller.SetAmount(amount)
+	}
+	if gas.Price != nil {
+		caller = caller.SetGasPrice(gas.Price)
+	}
+	if gas.Limit != 0 {
+		caller = caller.SetGasLimit(gas.Limit)
+	}
+	return caller.Call(ctx)
+}
+
+func (c *client) Transfer(ctx context.Context, to address.Address, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	caller := c.c.Transfer(to, amount)
+	if gas.Price != nil {
+		caller = caller.SetGasPrice(gas.Price)
+	}
+	if gas.Limit != 0 {
+		caller = caller.SetGasLimit(gas.Limit)
+	}
+	return caller.Call(ctx)
+}
+
+func (c *client) AddDeposit(ctx context.Context, bucketID uint64, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	caller := c.c.Staking().AddDeposit(bucketID, amount)
+	if gas.Price != nil {
+		caller = caller.SetGasPrice(gas.Price)
+	}
+	if gas.Limit != 0 {
+		caller = caller.SetGasLimit(gas.Limit)
+	}
+	return caller.Call(ctx)
+}
+
+func (c *client) ClaimReward(ctx context.Context, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	caller := c.c.ClaimReward(amount)
+	if gas.Price != nil {
+		caller = caller.SetGasPrice(gas.Price)
+	}
+	if gas.Limit != 0 {
+		caller = caller.SetGasLimit(gas.Limit)
+	}
+	return caller.Call(ctx)
+}
+
+func (c *client) GetReceipt(ctx context.Context, h hash.Hash256) (*iotextypes.Receipt, error) {
+	resp, err := c.c.API().GetReceiptByAction(ctx, &iotexapi.GetReceiptByActionRequest{
+		ActionHash: hex.EncodeToString(h[:]),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.ReceiptInfo.Receipt, nil
+}
+
+func (c *client) HasAction(ctx context.Context, h hash.Hash256) (bool, error) {
+	_, err := c.c.API().GetActions(ctx, &iotexapi.GetActionsRequest{
+		Lookup: &iotexapi.GetActionsRequest_ByHash{
+			ByHash: &iotexapi.GetActionByHashRequest{
+				ActionHash:   hex.EncodeToString(h[:]),
+				CheckPending: true,
+			},
+		},
+	})
+	if err == nil {
+		return true, nil
+	}
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	}
+	return false, err
+}
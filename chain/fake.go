@@ -0,0 +1,509 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-antenna-go/v2/account"
+	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Action types recorded by FakeClient
+const (
+	ActionExecution = "execution"
+	ActionTransfer  = "transfer"
+	ActionDeposit   = "deposit"
+	ActionClaim     = "claim"
+)
+
+type (
+	// ReadHandler returns the outputs of a contract method called with args
+	ReadHandler func(args []interface{}) ([]interface{}, error)
+
+	// ExecuteHandler executes a contract method called with args and amount, an error reverts the execution
+	ExecuteHandler func(amount *big.Int, args []interface{}) error
+
+	// ReadStateHandler returns the state read by request
+	ReadStateHandler func(request *iotexapi.ReadStateRequest) (*iotexapi.ReadStateResponse, error)
+
+	// FakeAction is an action sent to FakeClient
+	FakeAction struct {
+		Hash     hash.Hash256
+		Nonce    uint64
+		Type     string
+		Contract string
+		Method   string
+		Args     []interface{}
+		To       string
+		BucketID uint64
+		Amount   *big.Int
+		Gas      Gas
+		Status   uint64
+	}
+
+	// FakeClient is an in-memory Client for tests. Contract methods and state reads are served by the registered
+	// handlers, and every action is mined immediately. Every action takes the next nonce, and a dropped one leaves
+	// its nonce unconfirmed until a later action is mined.
+	FakeClient struct {
+		mu        sync.Mutex
+		account   account.Account
+		meta      *iotextypes.ChainMeta
+		reads     map[string]ReadHandler
+		executes  map[string]ExecuteHandler
+		states    map[string]ReadStateHandler
+		actions   []*FakeAction
+		receipts  map[hash.Hash256]*iotextypes.Receipt
+		logs      []*iotextypes.Log
+		emitted   []*iotextypes.Log
+		failures  map[string]error
+		dropNext  bool
+		lostNext  error
+		balance   *big.Int
+		unclaimed *big.Int
+		gasPrice  *big.Int
+		nonce     uint64
+		confirmed uint64
+	}
+)
+
+// NewFakeClient returns a FakeClient sending actions with acc
+func NewFakeClient(acc account.Account) *FakeClient {
+	return &FakeClient{
+		account:   acc,
+		meta:      &iotextypes.ChainMeta{Epoch: &iotextypes.EpochData{}},
+		reads:     make(map[string]ReadHandler),
+		executes:  make(map[string]ExecuteHandler),
+		states:    make(map[string]ReadStateHandler),
+		receipts:  make(map[hash.Hash256]*iotextypes.Receipt),
+		failures:  make(map[string]error),
+		balance:   big.NewInt(0),
+		unclaimed: big.NewInt(0),
+		gasPrice:  big.NewInt(1),
+	}
+}
+
+func methodKey(contract string, method string) string {
+	return contract + "." + method
+}
+
+// HandleRead registers the handler of a read of method on contract
+func (f *FakeClient) HandleRead(contract string, method string, h ReadHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reads[methodKey(contract, method)] = h
+}
+
+// HandleExecute registers the handler of an execution of method on contract
+func (f *FakeClient) HandleExecute(contract string, method string, h ExecuteHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executes[methodKey(contract, method)] = h
+}
+
+// HandleReadState registers the handler of the state reads of protocol
+func (f *FakeClient) HandleReadState(protocol string, h ReadStateHandler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states[protocol] = h
+}
+
+// FailNext makes the next action of type fail to be sent with err
+func (f *FakeClient) FailNext(actionType string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[actionType] = err
+}
+
+// DropNext makes the next action be accepted but never mined
+func (f *FakeClient) DropNext() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropNext = true
+}
+
+// LoseNext makes the next action be accepted while sending it fails with err, as when the node times out
+func (f *FakeClient) LoseNext(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lostNext = err
+}
+
+// SetChainMeta sets the current height and epoch
+func (f *FakeClient) SetChainMeta(height uint64, epoch uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.meta = &iotextypes.ChainMeta{Height: height, Epoch: &iotextypes.EpochData{Num: epoch}}
+}
+
+// EmitLog emits a log of contract in the action being executed, which an execute handler calls. The log is kept
+// only if the action is mined successfully.
+func (f *FakeClient) EmitLog(contract string, topics [][]byte, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.emitted = append(f.emitted, &iotextypes.Log{ContractAddress: contract, Topics: topics, Data: data})
+}
+
+// SetBalance sets the balance of the account
+func (f *FakeClient) SetBalance(balance *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.balance = new(big.Int).Set(balance)
+}
+
+// Balance returns the balance of the account
+func (f *FakeClient) Balance() *big.Int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(f.balance)
+}
+
+// SetUnclaimedBalance sets the unclaimed reward of the account in the rewarding protocol
+func (f *FakeClient) SetUnclaimedBalance(balance *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.unclaimed = new(big.Int).Set(balance)
+}
+
+// SetGasPrice sets the gas price the node suggests
+func (f *FakeClient) SetGasPrice(price *big.Int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.gasPrice = new(big.Int).Set(price)
+}
+
+// Actions returns the actions sent so far
+func (f *FakeClient) Actions() []*FakeAction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*FakeAction(nil), f.actions...)
+}
+
+// Account implements Client
+func (f *FakeClient) Account() account.Account {
+	return f.account
+}
+
+// ReadState implements Client
+func (f *FakeClient) ReadState(ctx context.Context, request *iotexapi.ReadStateRequest) (*iotexapi.ReadStateResponse, error) {
+	f.mu.Lock()
+	h, ok := f.states[string(request.ProtocolID)]
+	unclaimed := f.unclaimed.String()
+	f.mu.Unlock()
+	if ok {
+		return h(request)
+	}
+	if string(request.ProtocolID) == "rewarding" {
+		return &iotexapi.ReadStateResponse{Data: []byte(unclaimed)}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no state handler of protocol %s", request.ProtocolID)
+}
+
+// GetChainMeta implements Client
+func (f *FakeClient) GetChainMeta(ctx context.Context) (*iotextypes.ChainMeta, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.meta, nil
+}
+
+// GetEpochHeight implements Client, an epoch starts at the height of its number
+func (f *FakeClient) GetEpochHeight(ctx context.Context, epoch uint64) (uint64, error) {
+	return epoch, nil
+}
+
+// GetLogs implements Client, filtering by the addresses and the first topics of filter
+func (f *FakeClient) GetLogs(ctx context.Context, filter *iotexapi.LogsFilter, fromHeight uint64, count uint64) ([]*iotextypes.Log, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	match := func(values [][]byte, v []byte) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, value := range values {
+			if string(value) == string(v) {
+				return true
+			}
+		}
+		return false
+	}
+	var addresses [][]byte
+	for _, addr := range filter.GetAddress() {
+		addresses = append(addresses, []byte(addr))
+	}
+	var topics [][]byte
+	if len(filter.GetTopics()) > 0 {
+		topics = filter.Topics[0].Topic
+	}
+	var logs []*iotextypes.Log
+	for _, log := range f.logs {
+		if log.BlkHeight < fromHeight || log.BlkHeight >= fromHeight+count {
+			continue
+		}
+		if match(addresses, []byte(log.ContractAddress)) && match(topics, log.Topics[0]) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// GetBalance implements Client
+func (f *FakeClient) GetBalance(ctx context.Context) (*big.Int, error) {
+	return f.Balance(), nil
+}
+
+// GetNonce implements Client
+func (f *FakeClient) GetNonce(ctx context.Context) (uint64, uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.confirmed, f.nonce + 1, nil
+}
+
+// GetActionByNonce implements Client
+func (f *FakeClient) GetActionByNonce(ctx context.Context, nonce uint64) (*iotexapi.ActionInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, act := range f.actions {
+		if _, ok := f.receipts[act.Hash]; act.Nonce != nonce || !ok {
+			continue
+		}
+		core := &iotextypes.ActionCore{Nonce: act.Nonce, GasLimit: act.Gas.Limit}
+		if act.Type == ActionTransfer {
+			core.Action = &iotextypes.ActionCore_Transfer{
+				Transfer: &iotextypes.Transfer{Amount: act.Amount.String(), Recipient: act.To},
+			}
+		}
+		return &iotexapi.ActionInfo{
+			Action:  &iotextypes.Action{Core: core},
+			ActHash: hex.EncodeToString(act.Hash[:]),
+			Sender:  f.account.Address().String(),
+		}, nil
+	}
+	return nil, nil
+}
+
+// SuggestGasPrice implements Client
+func (f *FakeClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return new(big.Int).Set(f.gasPrice), nil
+}
+
+// EstimateExecution implements Client, estimating the intrinsic gas plus 100 per byte of the call data
+func (f *FakeClient) EstimateExecution(ctx context.Context, contract address.Address, contractABI abi.ABI, amount *big.Int, method string, args ...interface{}) (uint64, error) {
+	data, err := contractABI.Pack(method, args...)
+	if err != nil {
+		return 0, err
+	}
+	return IntrinsicGas + 100*uint64(len(data)), nil
+}
+
+// EstimateTransfer implements Client
+func (f *FakeClient) EstimateTransfer(ctx context.Context, to address.Address, amount *big.Int) (uint64, error) {
+	return IntrinsicGas, nil
+}
+
+// ReadContract implements Client
+func (f *FakeClient) ReadContract(ctx context.Context, contract address.Address, contractABI abi.ABI, method string, args ...interface{}) (Data, error) {
+	f.mu.Lock()
+	h, ok := f.reads[methodKey(contract.String(), method)]
+	f.mu.Unlock()
+	if !ok {
+		return Data{}, fmt.Errorf("no read handler of %s on %s", method, contract.String())
+	}
+	m, ok := contractABI.Methods[method]
+	if !ok {
+		return Data{}, fmt.Errorf("method %s is not in abi", method)
+	}
+	if _, err := contractABI.Pack(method, args...); err != nil {
+		return Data{}, err
+	}
+	outputs, err := h(args)
+	if err != nil {
+		return Data{}, err
+	}
+	raw, err := m.Outputs.Pack(outputs...)
+	if err != nil {
+		return Data{}, err
+	}
+	return Data{abi: contractABI, method: method, Raw: raw}, nil
+}
+
+// ExecuteContract implements Client
+func (f *FakeClient) ExecuteContract(ctx context.Context, contract address.Address, contractABI abi.ABI, amount *big.Int, gas Gas, method string, args ...interface{}) (hash.Hash256, error) {
+	if _, err := contractABI.Pack(method, args...); err != nil {
+		return hash.ZeroHash256, err
+	}
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+	drop, err := f.prepare(ActionExecution)
+	if err != nil {
+		return hash.ZeroHash256, err
+	}
+	f.mu.Lock()
+	h, ok := f.executes[methodKey(contract.String(), method)]
+	f.mu.Unlock()
+	var execErr error
+	switch {
+	case drop:
+	case !ok:
+		execErr = fmt.Errorf("no execute handler of %s on %s", method, contract.String())
+	default:
+		execErr = h(new(big.Int).Set(amount), args)
+	}
+	return f.send(drop, &FakeAction{
+		Type:     ActionExecution,
+		Contract: contract.String(),
+		Method:   method,
+		Args:     args,
+		Amount:   amount,
+		Gas:      gas,
+	}, execErr)
+}
+
+// Transfer implements Client
+func (f *FakeClient) Transfer(ctx context.Context, to address.Address, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	drop, err := f.prepare(ActionTransfer)
+	if err != nil {
+		return hash.ZeroHash256, err
+	}
+	return f.send(drop, &FakeAction{
+		Type:   ActionTransfer,
+		To:     to.String(),
+		Amount: amount,
+		Gas:    gas,
+	}, nil)
+}
+
+// AddDeposit implements Client
+func (f *FakeClient) AddDeposit(ctx context.Context, bucketID uint64, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	drop, err := f.prepare(ActionDeposit)
+	if err != nil {
+		return hash.ZeroHash256, err
+	}
+	return f.send(drop, &FakeAction{
+		Type:     ActionDeposit,
+		BucketID: bucketID,
+		Amount:   amount,
+		Gas:      gas,
+	}, nil)
+}
+
+// ClaimReward implements Client
+func (f *FakeClient) ClaimReward(ctx context.Context, amount *big.Int, gas Gas) (hash.Hash256, error) {
+	drop, err := f.prepare(ActionClaim)
+	if err != nil {
+		return hash.ZeroHash256, err
+	}
+	f.mu.Lock()
+	var claimErr error
+	if amount.Cmp(f.unclaimed) > 0 {
+		claimErr = fmt.Errorf("claim %s more than unclaimed balance %s", amount, f.unclaimed)
+	}
+	f.mu.Unlock()
+	return f.send(drop, &FakeAction{
+		Type:   ActionClaim,
+		Amount: amount,
+		Gas:    gas,
+	}, claimErr)
+}
+
+// prepare returns the injected failure of the next action of actionType, and whether the action is to be dropped
+func (f *FakeClient) prepare(actionType string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err, ok := f.failures[actionType]; ok {
+		delete(f.failures, actionType)
+		return false, err
+	}
+	drop := f.dropNext
+	f.dropNext = false
+	return drop, nil
+}
+
+// send records the action and mines it unless it is dropped, with a failure status if execErr is not nil
+func (f *FakeClient) send(drop bool, act *FakeAction, execErr error) (hash.Hash256, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nonce++
+	var nonce [8]byte
+	binary.BigEndian.PutUint64(nonce[:], f.nonce)
+	act.Hash = hash.Hash256b(append([]byte(f.account.Address().String()), nonce[:]...))
+	act.Nonce = f.nonce
+	act.Amount = new(big.Int).Set(act.Amount)
+	f.actions = append(f.actions, act)
+	emitted := f.emitted
+	f.emitted = nil
+	lost := f.lostNext
+	f.lostNext = nil
+	if drop {
+		if lost != nil {
+			return hash.ZeroHash256, lost
+		}
+		return act.Hash, nil
+	}
+
+	f.confirmed = act.Nonce
+	act.Status = 1
+	if execErr != nil {
+		act.Status = 0
+	} else {
+		for _, log := range emitted {
+			log.BlkHeight = f.meta.Height
+			log.ActHash = act.Hash[:]
+			log.Index = uint32(len(f.logs))
+			f.logs = append(f.logs, log)
+		}
+		switch act.Type {
+		case ActionClaim:
+			f.unclaimed.Sub(f.unclaimed, act.Amount)
+			f.balance.Add(f.balance, act.Amount)
+		default:
+			f.balance.Sub(f.balance, act.Amount)
+		}
+	}
+	f.receipts[act.Hash] = &iotextypes.Receipt{
+		Status:      act.Status,
+		BlkHeight:   f.meta.Height,
+		ActHash:     act.Hash[:],
+		GasConsumed: act.Gas.Limit,
+	}
+	if lost != nil {
+		return hash.ZeroHash256, lost
+	}
+	return act.Hash, nil
+}
+
+// GetReceipt implements ReceiptReader
+func (f *FakeClient) GetReceipt(ctx context.Context, h hash.Hash256) (*iotextypes.Receipt, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	receipt, ok := f.receipts[h]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "receipt of %x is not found", h)
+	}
+	return receipt, nil
+}
+
+// HasAction implements ReceiptReader
+func (f *FakeClient) HasAction(ctx context.Context, h hash.Hash256) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.receipts[h]
+	return ok, nil
+}
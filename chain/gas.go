@@ -0,0 +1,140 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// IntrinsicGas is the gas of a staking deposit or a reward claim without payload, which the node cannot estimate
+const IntrinsicGas = 10000
+
+// ErrGasLimitExceeded indicates the estimated gas of an action is more than the configured maximum
+var ErrGasLimitExceeded = errors.New("estimated gas exceeds the maximum limit")
+
+// GasEstimator returns the gas of the actions hermes sends. The configured price, and limit of contract executions,
+// are used as they are, otherwise the price is suggested by the node and the limit is the estimate of the node raised
+// by the multiplier, both capped by the configured maximums.
+type GasEstimator struct {
+	price      *big.Int
+	limit      uint64
+	multiplier float64
+	maxPrice   *big.Int
+	maxLimit   uint64
+}
+
+// NewGasEstimator returns a gas estimator of cfg
+func NewGasEstimator(cfg config.Gas) *GasEstimator {
+	e := &GasEstimator{
+		limit:      cfg.Limit,
+		multiplier: cfg.Multiplier,
+		maxLimit:   cfg.MaxLimit,
+	}
+	if e.multiplier < 1 {
+		e.multiplier = 1
+	}
+	if cfg.Price.IsSet() {
+		e.price = cfg.Price.Int()
+	}
+	if cfg.MaxPrice.IsSet() {
+		e.maxPrice = cfg.MaxPrice.Int()
+	}
+	return e
+}
+
+// Fee returns the most the action pays for gas
+func (g Gas) Fee() *big.Int {
+	return new(big.Int).Mul(g.Price, new(big.Int).SetUint64(g.Limit))
+}
+
+// Price returns the gas price of the next action
+func (e *GasEstimator) Price(ctx context.Context, c Client) (*big.Int, error) {
+	if e.price != nil {
+		return new(big.Int).Set(e.price), nil
+	}
+	price, err := c.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to suggest gas price")
+	}
+	if e.maxPrice != nil && price.Cmp(e.maxPrice) > 0 {
+		price.Set(e.maxPrice)
+	}
+	return price, nil
+}
+
+// Execution returns the gas of executing method of contract with args and amount
+func (e *GasEstimator) Execution(ctx context.Context, c Client, contract address.Address, abi abi.ABI, amount *big.Int, method string, args ...interface{}) (Gas, error) {
+	return e.gas(ctx, c, e.limit, func() (uint64, error) {
+		return c.EstimateExecution(ctx, contract, abi, amount, method, args...)
+	})
+}
+
+// Transfer returns the gas of transferring amount to to, whose limit is always estimated
+func (e *GasEstimator) Transfer(ctx context.Context, c Client, to address.Address, amount *big.Int) (Gas, error) {
+	return e.gas(ctx, c, 0, func() (uint64, error) {
+		return c.EstimateTransfer(ctx, to, amount)
+	})
+}
+
+// MaxExecution returns the largest estimate of a contract execution which fits the configured limit, or the maximum
+// limit with the multiplier, 0 if executions are not limited
+func (e *GasEstimator) MaxExecution() uint64 {
+	if e.limit != 0 {
+		return e.limit
+	}
+	return uint64(float64(e.maxLimit) / e.multiplier)
+}
+
+// Intrinsic returns the gas of a staking deposit or a reward claim, whose limit is always IntrinsicGas
+func (e *GasEstimator) Intrinsic(ctx context.Context, c Client) (Gas, error) {
+	price, err := e.Price(ctx, c)
+	if err != nil {
+		return Gas{}, err
+	}
+	return Gas{Price: price, Limit: IntrinsicGas}, nil
+}
+
+// UpperBound returns the gas of an action which cannot be estimated in advance, at the configured or maximum limit
+func (e *GasEstimator) UpperBound(ctx context.Context, c Client) (Gas, error) {
+	price, err := e.Price(ctx, c)
+	if err != nil {
+		return Gas{}, err
+	}
+	limit := e.limit
+	if limit == 0 {
+		limit = e.maxLimit
+	}
+	return Gas{Price: price, Limit: limit}, nil
+}
+
+// gas returns the gas at the current price, with limit if it is not 0 or the estimate otherwise
+func (e *GasEstimator) gas(ctx context.Context, c Client, limit uint64, estimate func() (uint64, error)) (Gas, error) {
+	price, err := e.Price(ctx, c)
+	if err != nil {
+		return Gas{}, err
+	}
+	if limit != 0 {
+		return Gas{Price: price, Limit: limit}, nil
+	}
+	estimated, err := estimate()
+	if err != nil {
+		return Gas{}, errors.Wrap(err, "failed to estimate gas")
+	}
+	limit = uint64(math.Ceil(float64(estimated) * e.multiplier))
+	if e.maxLimit != 0 && limit > e.maxLimit {
+		return Gas{}, errors.Wrapf(ErrGasLimitExceeded, "%d with multiplier %g, maximum %d", estimated, e.multiplier, e.maxLimit)
+	}
+	return Gas{Price: price, Limit: limit}, nil
+}
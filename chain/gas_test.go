@@ -0,0 +1,88 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/iotexproject/iotex-antenna-go/v2/account"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+const testABI = `[{"constant":false,"inputs":[{"name":"amounts","type":"uint256[]"}],"name":"send","outputs":[],"payable":true,"stateMutability":"payable","type":"function"}]`
+
+func TestGasEstimator(t *testing.T) {
+	require := require.New(t)
+
+	acc, err := account.NewAccount()
+	require.NoError(err)
+	c := NewFakeClient(acc)
+	c.SetGasPrice(big.NewInt(2000))
+	contractABI, err := abi.JSON(strings.NewReader(testABI))
+	require.NoError(err)
+	contract := acc.Address()
+	amounts := []*big.Int{big.NewInt(1), big.NewInt(2)}
+	ctx := context.Background()
+
+	// a configured price and limit are used as they are
+	gas, err := NewGasEstimator(config.Gas{
+		Price: config.NewBigInt(big.NewInt(1000)),
+		Limit: 50000,
+	}).Execution(ctx, c, contract, contractABI, nil, "send", amounts)
+	require.NoError(err)
+	require.Equal("1000", gas.Price.String())
+	require.Equal(uint64(50000), gas.Limit)
+	require.Equal("50000000", gas.Fee().String())
+
+	// except the limit of a transfer, which is always estimated
+	gas, err = NewGasEstimator(config.Gas{
+		Price: config.NewBigInt(big.NewInt(1000)),
+		Limit: 50000,
+	}).Transfer(ctx, c, contract, big.NewInt(1))
+	require.NoError(err)
+	require.Equal(uint64(IntrinsicGas), gas.Limit)
+
+	// otherwise the suggested price and the estimate raised by the multiplier
+	estimated, err := c.EstimateExecution(ctx, contract, contractABI, nil, "send", amounts)
+	require.NoError(err)
+	estimator := NewGasEstimator(config.Gas{Multiplier: 1.5, MaxLimit: 7000000})
+	gas, err = estimator.Execution(ctx, c, contract, contractABI, nil, "send", amounts)
+	require.NoError(err)
+	require.Equal("2000", gas.Price.String())
+	require.Equal(estimated*3/2, gas.Limit)
+
+	gas, err = estimator.Transfer(ctx, c, contract, big.NewInt(1))
+	require.NoError(err)
+	require.Equal(uint64(IntrinsicGas*3/2), gas.Limit)
+
+	gas, err = estimator.Intrinsic(ctx, c)
+	require.NoError(err)
+	require.Equal(uint64(IntrinsicGas), gas.Limit)
+
+	gas, err = estimator.UpperBound(ctx, c)
+	require.NoError(err)
+	require.Equal(uint64(7000000), gas.Limit)
+
+	// the price is capped, and an estimate above the maximum limit is refused
+	estimator = NewGasEstimator(config.Gas{
+		Multiplier: 1.5,
+		MaxPrice:   config.NewBigInt(big.NewInt(1500)),
+		MaxLimit:   estimated,
+	})
+	price, err := estimator.Price(ctx, c)
+	require.NoError(err)
+	require.Equal("1500", price.String())
+	_, err = estimator.Execution(ctx, c, contract, contractABI, nil, "send", amounts)
+	require.Equal(ErrGasLimitExceeded, errors.Cause(err))
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/metrics"
+)
+
+var (
+	// ErrNotMined indicates the action is still pending when the waiter times out
+	ErrNotMined = errors.New("action is not mined yet")
+	// ErrReverted indicates the action is mined with a failure status
+	ErrReverted = errors.New("action is reverted")
+	// ErrDropped indicates the action is neither mined nor pending any more
+	ErrDropped = errors.New("action is dropped")
+)
+
+// ReceiptWaiter polls the receipt of an action until it is mined
+type ReceiptWaiter struct {
+	timeout     time.Duration
+	interval    time.Duration
+	maxInterval time.Duration
+	backoff     float64
+}
+
+// NewReceiptWaiter returns a receipt waiter of cfg
+func NewReceiptWaiter(cfg config.Receipt) *ReceiptWaiter {
+	return &ReceiptWaiter{
+		timeout:     cfg.Timeout,
+		interval:    cfg.Interval,
+		maxInterval: cfg.MaxInterval,
+		backoff:     cfg.Backoff,
+	}
+}
+
+// Wait returns the receipt of the action h once it is mined successfully. It returns an error caused by ErrReverted
+// if the action failed, ErrDropped if the node no longer knows the action, and ErrNotMined if the action is still
+// pending after the timeout.
+func (w *ReceiptWaiter) Wait(ctx context.Context, api ReceiptReader, h hash.Hash256) (*iotextypes.Receipt, error) {
+	receipt, err := w.wait(ctx, api, h)
+	switch {
+	case err == nil, errors.Cause(err) == context.Canceled:
+	case errors.Cause(err) == ErrReverted:
+		metrics.ReceiptFailures.WithLabelValues("reverted").Inc()
+	case errors.Cause(err) == ErrDropped:
+		metrics.ReceiptFailures.WithLabelValues("dropped").Inc()
+	case errors.Cause(err) == ErrNotMined:
+		metrics.ReceiptFailures.WithLabelValues("not_mined").Inc()
+	default:
+		metrics.ReceiptFailures.WithLabelValues("error").Inc()
+	}
+	return receipt, err
+}
+
+func (w *ReceiptWaiter) wait(ctx context.Context, api ReceiptReader, h hash.Hash256) (*iotextypes.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	actionHash := hex.EncodeToString(h[:])
+	interval := w.interval
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, errors.Wrapf(ErrNotMined, "action %s after %s", actionHash, w.timeout)
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		receipt, err := api.GetReceipt(ctx, h)
+		switch {
+		case err == nil:
+			if receipt.Status != 1 {
+				return receipt, errors.Wrapf(ErrReverted, "action %s with status %d", actionHash, receipt.Status)
+			}
+			return receipt, nil
+		case status.Code(err) != codes.NotFound:
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get receipt of action %s", actionHash)
+		}
+
+		pending, err := api.HasAction(ctx, h)
+		if err != nil && ctx.Err() == nil {
+			return nil, errors.Wrapf(err, "failed to get action %s", actionHash)
+		}
+		if err == nil && !pending {
+			return nil, errors.Wrapf(ErrDropped, "action %s", actionHash)
+		}
+
+		interval = time.Duration(float64(interval) * w.backoff)
+		if interval > w.maxInterval {
+			interval = w.maxInterval
+		}
+	}
+}
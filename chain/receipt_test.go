@@ -0,0 +1,79 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package chain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/metrics"
+)
+
+type receiptReader struct {
+	// minedAfter is the number of receipt queries before the receipt is found
+	minedAfter int
+	status     uint64
+	pending    bool
+	queries    int
+}
+
+func (r *receiptReader) GetReceipt(context.Context, hash.Hash256) (*iotextypes.Receipt, error) {
+	r.queries++
+	if r.queries <= r.minedAfter {
+		return nil, status.Error(codes.NotFound, "receipt not found")
+	}
+	return &iotextypes.Receipt{Status: r.status}, nil
+}
+
+func (r *receiptReader) HasAction(context.Context, hash.Hash256) (bool, error) {
+	return r.pending, nil
+}
+
+func TestReceiptWaiter(t *testing.T) {
+	require := require.New(t)
+
+	waiter := NewReceiptWaiter(config.Receipt{
+		Timeout:     100 * time.Millisecond,
+		Interval:    time.Millisecond,
+		MaxInterval: 5 * time.Millisecond,
+		Backoff:     2,
+	})
+	ctx := context.Background()
+
+	receipt, err := waiter.Wait(ctx, &receiptReader{minedAfter: 3, status: 1, pending: true}, hash.ZeroHash256)
+	require.NoError(err)
+	require.Equal(uint64(1), receipt.Status)
+
+	_, err = waiter.Wait(ctx, &receiptReader{status: 0, pending: true}, hash.ZeroHash256)
+	require.Equal(ErrReverted, errors.Cause(err))
+
+	_, err = waiter.Wait(ctx, &receiptReader{minedAfter: 1000, pending: true}, hash.ZeroHash256)
+	require.Equal(ErrNotMined, errors.Cause(err))
+
+	_, err = waiter.Wait(ctx, &receiptReader{minedAfter: 1000}, hash.ZeroHash256)
+	require.Equal(ErrDropped, errors.Cause(err))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = waiter.Wait(cancelled, &receiptReader{minedAfter: 1000, pending: true}, hash.ZeroHash256)
+	require.Equal(context.Canceled, err)
+
+	for _, reason := range []string{"reverted", "not_mined", "dropped"} {
+		require.Equal(float64(1), testutil.ToFloat64(metrics.ReceiptFailures.WithLabelValues(reason)))
+	}
+	require.Equal(float64(0), testutil.ToFloat64(metrics.ReceiptFailures.WithLabelValues("error")))
+}
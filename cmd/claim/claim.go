@@ -8,74 +8,72 @@ package claim
 
 import (
 	"context"
-	"encoding/hex"
-	"fmt"
 	"math/big"
-	"strconv"
-	"time"
 
-	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
+	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-proto/golang/iotexapi"
 	"github.com/iotexproject/iotex-proto/golang/protocol"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
-	"github.com/iotexproject/iotex-hermes/util"
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/notify"
 )
 
 // ClaimCmd is the claim command
 var ClaimCmd = &cobra.Command{
-	Use:   "claim DELEGATE",
-	Short: "Claim rewards for delegate",
-	Args:  cobra.MinimumNArgs(1),
+	Use:   "claim",
+	Short: "Claim the rewards of the vault",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
-		return Reward()
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return Reward(context.Background(), cfg, c)
 	},
 }
 
 // Reward is claim reward from contract
-func Reward() error {
-	pwd := util.MustFetchNonEmptyParam("VAULT_PASSWORD")
-	account, err := util.GetVaultAccount(pwd)
-	if err != nil {
-		return err
-	}
-
-	endpoint := util.MustFetchNonEmptyParam("IO_ENDPOINT")
-	conn, err := iotex.NewDefaultGRPCConn(endpoint)
-	if err != nil {
-		return err
-	}
-	defer conn.Close()
-	c := iotex.NewAuthedClient(iotexapi.NewAPIServiceClient(conn), account)
-
+func Reward(ctx context.Context, cfg *config.Config, c chain.Client) error {
 	// get current epoch and block height
-	resp, err := c.API().GetChainMeta(context.Background(), &iotexapi.GetChainMetaRequest{})
+	meta, err := c.GetChainMeta(ctx)
 	if err != nil {
 		return err
 	}
-	curEpoch := resp.ChainMeta.Epoch.Num
-	curHeight := resp.ChainMeta.Height
 
-	fmt.Printf("Current Epoch Number: %d\n", curEpoch)
-	fmt.Printf("Current Block Height: %d\n", curHeight)
-
-	unclaimedBalance, err := getUnclaimedBalance(c)
+	unclaimedBalance, err := GetUnclaimedBalance(ctx, c)
 	if err != nil {
 		return err
 	}
-	fmt.Printf("Unclaimed Balance: %s\n", unclaimedBalance.String())
-	return claim(c, unclaimedBalance)
+	logger.Ctx(ctx).Info("claiming rewards",
+		zap.Uint64("epoch", meta.Epoch.Num),
+		zap.Uint64("height", meta.Height),
+		zap.String("unclaimedBalance", unclaimedBalance.String()))
+	return claim(ctx, cfg, c, unclaimedBalance)
 }
 
-func getUnclaimedBalance(c iotex.AuthedClient) (*big.Int, error) {
+// GetUnclaimedBalance returns the unclaimed reward of the account of c
+func GetUnclaimedBalance(ctx context.Context, c chain.Client) (*big.Int, error) {
 	request := &iotexapi.ReadStateRequest{
 		ProtocolID: []byte(protocol.RewardingProtocolID),
 		MethodName: []byte(protocol.ReadUnclaimedBalanceMethodName),
 		Arguments:  [][]byte{[]byte(c.Account().Address().String())},
 	}
-	response, err := c.API().ReadState(context.Background(), request)
+	response, err := c.ReadState(ctx, request)
 	if err != nil {
 		return nil, err
 	}
@@ -86,28 +84,24 @@ func getUnclaimedBalance(c iotex.AuthedClient) (*big.Int, error) {
 	return unclaimedBlance, nil
 }
 
-func claim(c iotex.AuthedClient, unclaimedBalance *big.Int) error {
-	ctx := context.Background()
-	hash, err := c.ClaimReward(unclaimedBalance).Call(ctx)
-	if err != nil {
-		return err
+func claim(ctx context.Context, cfg *config.Config, c chain.Client, unclaimedBalance *big.Int) error {
+	gas, err := chain.NewGasEstimator(cfg.Gas).Intrinsic(ctx, c)
+	var h hash.Hash256
+	if err == nil {
+		h, err = c.ClaimReward(ctx, unclaimedBalance, gas)
 	}
-	sleepIntervalStr := util.MustFetchNonEmptyParam("SLEEP_INTERVAL")
-	sleepInterval, err := strconv.Atoi(sleepIntervalStr)
-	if err != nil {
-		return err
+	if err == nil {
+		_, err = chain.NewReceiptWaiter(cfg.Receipt).Wait(ctx, c, h)
 	}
-	time.Sleep(time.Duration(sleepInterval) * time.Second)
-
-	resp, err := c.API().GetReceiptByAction(ctx, &iotexapi.GetReceiptByActionRequest{
-		ActionHash: hex.EncodeToString(hash[:]),
-	})
 	if err != nil {
+		err = errors.Wrap(err, "claim rewards failed")
+		notify.Send(ctx, &notify.Event{
+			Type:    notify.EventClaimFailed,
+			Message: err.Error(),
+			Fields:  map[string]string{"amount": unclaimedBalance.String()},
+		})
 		return err
 	}
-	if resp.ReceiptInfo.Receipt.Status != 1 {
-		return errors.Errorf("claim rewards failed: %x", hash)
-	}
-	fmt.Println("successfully claim rewards")
+	logger.Ctx(ctx).Info("claimed rewards", logger.ActionHash(h))
 	return nil
 }
@@ -7,20 +7,23 @@
 package claim
 
 import (
+	"context"
 	"math/big"
-	"os"
 	"testing"
+	"time"
 
 	"github.com/iotexproject/iotex-antenna-go/v2/account"
 	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
 	"github.com/iotexproject/iotex-proto/golang/iotexapi"
 	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
 )
 
 const (
 	ioEndpoint     = "api.testnet.iotex.one:443"
 	testPrivateKey = "2394db684d2d14586e16ec597ce9222a2e552265a58da2a9218a09e3ccff8893"
-	sleepInterval  = "20"
 )
 
 func TestClaimReward(t *testing.T) {
@@ -33,12 +36,36 @@ func TestClaimReward(t *testing.T) {
 	require.NoError(err)
 	defer conn.Close()
 
-	c := iotex.NewAuthedClient(iotexapi.NewAPIServiceClient(conn), account)
+	c := chain.NewClient(iotexapi.NewAPIServiceClient(conn), account)
 
-	unClaimedBalance, err := getUnclaimedBalance(c)
+	unClaimedBalance, err := GetUnclaimedBalance(context.Background(), c)
 	require.NoError(err)
 	require.True(unClaimedBalance.Sign() > 0)
 
-	os.Setenv("SLEEP_INTERVAL", sleepInterval)
-	require.NoError(claim(c, big.NewInt(1)))
+	cfg := &config.Config{Receipt: config.Default.Receipt}
+	require.NoError(claim(context.Background(), cfg, c, big.NewInt(1)))
+}
+
+func TestReward(t *testing.T) {
+	require := require.New(t)
+
+	account, err := account.HexStringToAccount(testPrivateKey)
+	require.NoError(err)
+	c := chain.NewFakeClient(account)
+	c.SetChainMeta(1000, 30)
+	c.SetUnclaimedBalance(big.NewInt(100))
+
+	cfg := &config.Config{Receipt: config.Receipt{
+		Timeout: time.Second, Interval: time.Millisecond, MaxInterval: time.Millisecond, Backoff: 1,
+	}}
+	require.NoError(Reward(context.Background(), cfg, c))
+	require.Equal("100", c.Balance().String())
+
+	unclaimedBalance, err := GetUnclaimedBalance(context.Background(), c)
+	require.NoError(err)
+	require.Equal(0, unclaimedBalance.Sign())
+
+	c.SetUnclaimedBalance(big.NewInt(100))
+	c.DropNext()
+	require.Error(Reward(context.Background(), cfg, c))
 }
@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Cycle statuses, in the order a distribution cycle goes through them
+const (
+	CycleNew                = "new"
+	CycleClaimed            = "claimed"
+	CycleBookkeepingFetched = "bookkeeping_fetched"
+	CycleDistributing       = "distributing"
+	CycleCommitted          = "committed"
+	CycleDepositsSent       = "deposits_sent"
+)
+
+// Cycle distribution cycle model
+type Cycle struct {
+	gorm.Model
+
+	StartEpoch uint64
+	EndEpoch   uint64 `gorm:"unique_index:idx_cycles_end_epoch"`
+	Status     string `gorm:"type:varchar(20);index:idx_cycles_status"`
+	// DistributedDelegates is the number of delegates, in distribution order, whose rewards are fully sent
+	DistributedDelegates int
+	Failures             int
+	ErrorMessage         string `gorm:"type:text"`
+}
+
+// TableName table name of Cycle
+func (Cycle) TableName() string {
+	return "cycles"
+}
+
+// Save insert or update cycle
+func (t *Cycle) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		return tx.Create(t).Error
+	}
+	return tx.Save(t).Error
+}
+
+// Advance moves the cycle to status and persists it
+func (t *Cycle) Advance(status string) error {
+	t.Status = status
+	t.ErrorMessage = ""
+	return t.Save(nil)
+}
+
+// Fail records a failure of the current step and persists it
+func (t *Cycle) Fail(err error) error {
+	t.Failures++
+	t.ErrorMessage = err.Error()
+	return t.Save(nil)
+}
+
+// FindUnfinishedCycle find the earliest cycle which has not sent its deposits yet, nil if there is none
+func FindUnfinishedCycle() (*Cycle, error) {
+	var result Cycle
+	err := db.Where("status <> ?", CycleDepositsSent).Order("end_epoch asc").First(&result).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// FindCyclesByLimit find the latest cycles by limit
+func FindCyclesByLimit(limit int32) (result []Cycle, err error) {
+	err = db.Limit(limit).Order("end_epoch desc").Find(&result).Error
+	return
+}
+
+// FindCycleByEndEpoch find the cycle of the window ending at endEpoch, nil if there is none
+func FindCycleByEndEpoch(endEpoch uint64) (*Cycle, error) {
+	var result Cycle
+	err := db.Where("end_epoch = ?", endEpoch).First(&result).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -9,7 +9,7 @@ import (
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 
 	"github.com/iotexproject/iotex-hermes/cmd/key"
-	"github.com/iotexproject/iotex-hermes/util"
+	"github.com/iotexproject/iotex-hermes/config"
 )
 
 var db *gorm.DB
@@ -17,23 +17,31 @@ var privateKey *rsa.PrivateKey
 var publicKey *rsa.PublicKey
 
 // ConnectDatabase connect database
-func ConnectDatabase() error {
-	var err error
-	db, err = gorm.Open("mysql", util.MustFetchNonEmptyParam("DB_CONN"))
+func ConnectDatabase(cfg *config.Database) error {
+	gdb, err := gorm.Open("mysql", cfg.Conn)
 	if err != nil {
 		return fmt.Errorf("open database error: %v", err)
 	}
-	db.AutoMigrate(&DropRecord{})
-
-	privateKey, err = key.LoadPrivateKey(util.MustFetchNonEmptyParam("RSA_PRIVATE"))
+	priv, err := key.LoadPrivateKey(cfg.RSAPrivate)
 	if err != nil {
 		return fmt.Errorf("load private key error: %v", err)
 	}
-	publicKey, err = key.LoadPublicKey(util.MustFetchNonEmptyParam("RSA_PUBLIC"))
+	pub, err := key.LoadPublicKey(cfg.RSAPublic)
 	if err != nil {
 		return fmt.Errorf("load public key error: %v", err)
 	}
+	return SetDatabase(gdb, priv, pub)
+}
 
+// SetDatabase uses gdb and the keys signing the drop records, migrating the tables
+func SetDatabase(gdb *gorm.DB, priv *rsa.PrivateKey, pub *rsa.PublicKey) error {
+	if err := gdb.AutoMigrate(&DropRecord{}, &Cycle{}, &Plan{}, &Report{}, &ServiceFee{}, &FeeSweep{}, &AddressOverride{}).Error; err != nil {
+		return fmt.Errorf("migrate database error: %v", err)
+	}
+	db, privateKey, publicKey = gdb, priv, pub
+	if err := seedAddressOverrides(); err != nil {
+		return fmt.Errorf("seed address overrides error: %v", err)
+	}
 	return nil
 }
 
@@ -0,0 +1,46 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// ServiceFee is the service fee charged to a delegate in the cycle ending at EndEpoch, by its fee policy
+type ServiceFee struct {
+	gorm.Model
+
+	EndEpoch     uint64 `gorm:"unique_index:idx_service_fees_end_epoch_delegate_name"`
+	DelegateName string `gorm:"type:varchar(100);unique_index:idx_service_fees_end_epoch_delegate_name"`
+	Policy       string `gorm:"type:varchar(20)"`
+	Fee          string `gorm:"type:varchar(50)"`
+	Refund       string `gorm:"type:varchar(50)"`
+}
+
+// TableName table name of ServiceFee
+func (ServiceFee) TableName() string {
+	return "service_fees"
+}
+
+// Save insert or update service fee, replacing the fee of the same delegate and end epoch
+func (t *ServiceFee) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		var existing ServiceFee
+		err := tx.Where("end_epoch = ? AND delegate_name = ?", t.EndEpoch, t.DelegateName).First(&existing).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return tx.Create(t).Error
+		}
+		if err != nil {
+			return err
+		}
+		t.Model = existing.Model
+	}
+	return tx.Save(t).Error
+}
+
+// FindServiceFeesByEndEpoch find the service fees of the cycle ending at endEpoch
+func FindServiceFeesByEndEpoch(endEpoch uint64) (result []ServiceFee, err error) {
+	err = db.Where("end_epoch = ?", endEpoch).Order("delegate_name asc").Find(&result).Error
+	return
+}
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"fmt"
+	"math/big"
 
 	"github.com/jinzhu/gorm"
 
@@ -67,3 +68,45 @@ func FindNewDropRecordByLimit(limit int32) (result []DropRecord, err error) {
 	err = db.Limit(limit).Where("status = ?", "new").Find(&result).Error
 	return
 }
+
+// FindDropRecordsByEndEpoch find the drop records of the window ending at endEpoch
+func FindDropRecordsByEndEpoch(endEpoch uint64) (result []DropRecord, err error) {
+	err = db.Where("end_epoch = ?", endEpoch).Order("id asc").Find(&result).Error
+	return
+}
+
+// CountDropRecordsByStatus returns the number of drop records of every status
+func CountDropRecordsByStatus() (map[string]uint64, error) {
+	rows, err := db.Model(&DropRecord{}).Select("status, count(*)").Group("status").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]uint64)
+	for rows.Next() {
+		var status string
+		var count uint64
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		counts[status] = count
+	}
+	return counts, rows.Err()
+}
+
+// SumNewDropRecordAmounts returns the total amount of the drop records which are not sent yet
+func SumNewDropRecordAmounts() (*big.Int, error) {
+	var amounts []string
+	if err := db.Model(&DropRecord{}).Where("status = ?", "new").Pluck("amount", &amounts).Error; err != nil {
+		return nil, err
+	}
+	total := big.NewInt(0)
+	for _, s := range amounts {
+		amount, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid drop record amount %s", s)
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
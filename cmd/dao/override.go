@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// AddressOverride replaces a voter or staking address by Replacement in the distributions of the windows ending from
+// FromEpoch to ToEpoch, with no end if ToEpoch is 0. A removed override is soft deleted, so it is kept for audit
+type AddressOverride struct {
+	gorm.Model
+
+	Address     string `gorm:"type:varchar(41);index:idx_address_overrides_address"`
+	Replacement string `gorm:"type:varchar(41)"`
+	FromEpoch   uint64
+	ToEpoch     uint64
+	Reason      string `gorm:"type:text"`
+}
+
+// seededAddressOverrides are the remaps hard coded in earlier releases, kept for the deployments upgrading from them
+var seededAddressOverrides = []AddressOverride{
+	{
+		Address:     "io16y9wk2xnwurvtgmd2mds2gcdfe2lmzad6dcw29",
+		Replacement: "io16dkdajys8609qxf78wmmzssgfgvqkk0funzp0r",
+		Reason:      "remap of earlier releases",
+	},
+}
+
+// seedAddressOverrides adds the seeded overrides never added before, so one removed by an operator stays removed
+func seedAddressOverrides() error {
+	for _, seed := range seededAddressOverrides {
+		var count int
+		if err := db.Unscoped().Model(&AddressOverride{}).Where("address = ?", seed.Address).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		override := seed
+		if err := override.Save(nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TableName table name of AddressOverride
+func (AddressOverride) TableName() string {
+	return "address_overrides"
+}
+
+// Save insert or update address override
+func (t *AddressOverride) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		return tx.Create(t).Error
+	}
+	return tx.Save(t).Error
+}
+
+// Covers returns whether the override applies to the window ending at endEpoch
+func (t *AddressOverride) Covers(endEpoch uint64) bool {
+	return t.FromEpoch <= endEpoch && (t.ToEpoch == 0 || endEpoch <= t.ToEpoch)
+}
+
+// FindAddressOverrides find the address overrides by id, with the removed ones if unscoped
+func FindAddressOverrides(unscoped bool) (result []AddressOverride, err error) {
+	tx := db
+	if unscoped {
+		tx = tx.Unscoped()
+	}
+	err = tx.Order("id asc").Find(&result).Error
+	return
+}
+
+// FindAddressOverridesByEndEpoch find the address overrides applying to the window ending at endEpoch
+func FindAddressOverridesByEndEpoch(endEpoch uint64) (result []AddressOverride, err error) {
+	err = db.Where("from_epoch <= ? AND (to_epoch = 0 OR to_epoch >= ?)", endEpoch, endEpoch).
+		Order("id asc").Find(&result).Error
+	return
+}
+
+// DeleteAddressOverride removes the address override of id
+func DeleteAddressOverride(id uint) error {
+	result := db.Delete(&AddressOverride{}, "id = ?", id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("address override %d not found", id)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Plan is the distribution plan of a window persisted on its first fetch
+type Plan struct {
+	gorm.Model
+
+	EndEpoch uint64 `gorm:"unique_index:idx_plans_end_epoch"`
+	// Hash is the hex encoded content hash of the recipients and amounts of the plan
+	Hash    string `gorm:"type:varchar(64)"`
+	Content string `gorm:"type:mediumtext"`
+}
+
+// TableName table name of Plan
+func (Plan) TableName() string {
+	return "plans"
+}
+
+// Save insert or update plan
+func (t *Plan) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		return tx.Create(t).Error
+	}
+	return tx.Save(t).Error
+}
+
+// FindPlanByEndEpoch find the plan of the window ending at endEpoch, nil if there is none
+func FindPlanByEndEpoch(endEpoch uint64) (*Plan, error) {
+	var result Plan
+	err := db.Where("end_epoch = ?", endEpoch).First(&result).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
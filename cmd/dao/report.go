@@ -0,0 +1,52 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Report is the signed distribution report of a committed window
+type Report struct {
+	gorm.Model
+
+	EndEpoch uint64 `gorm:"unique_index:idx_reports_end_epoch"`
+	// Content is the JSON report, and Signature its hex encoded signature by the vault account Signer
+	Content   string `gorm:"type:mediumtext"`
+	Signature string `gorm:"type:varchar(130)"`
+	Signer    string `gorm:"type:varchar(41)"`
+}
+
+// TableName table name of Report
+func (Report) TableName() string {
+	return "reports"
+}
+
+// Save insert or update report, replacing the report of the same end epoch
+func (t *Report) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		existing, err := FindReportByEndEpoch(t.EndEpoch)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return tx.Create(t).Error
+		}
+		t.Model = existing.Model
+	}
+	return tx.Save(t).Error
+}
+
+// FindReportByEndEpoch find the report of the window ending at endEpoch, nil if there is none
+func FindReportByEndEpoch(endEpoch uint64) (*Report, error) {
+	var result Report
+	err := db.Where("end_epoch = ?", endEpoch).First(&result).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
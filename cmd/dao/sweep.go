@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// Statuses of a fee sweep
+const (
+	FeeSweepNew       = "new"
+	FeeSweepSent      = "sent"
+	FeeSweepCompleted = "completed"
+	FeeSweepError     = "error"
+)
+
+// FeeSweep is the transfer of the service fees of the cycle ending at EndEpoch to the treasury
+type FeeSweep struct {
+	gorm.Model
+
+	EndEpoch     uint64 `gorm:"unique_index:idx_fee_sweeps_end_epoch"`
+	Treasury     string `gorm:"type:varchar(41)"`
+	Amount       string `gorm:"type:varchar(50)"`
+	Hash         string `gorm:"type:varchar(64)"`
+	Nonce        uint64
+	Status       string `gorm:"type:varchar(15)"`
+	ErrorMessage string `gorm:"type:text"`
+}
+
+// TableName table name of FeeSweep
+func (FeeSweep) TableName() string {
+	return "fee_sweeps"
+}
+
+// Save insert or update fee sweep
+func (t *FeeSweep) Save(tx *gorm.DB) error {
+	if tx == nil {
+		tx = db
+	}
+	if t.ID == 0 {
+		return tx.Create(t).Error
+	}
+	return tx.Save(t).Error
+}
+
+// FindFeeSweepByEndEpoch find the fee sweep of the cycle ending at endEpoch, nil if there is none
+func FindFeeSweepByEndEpoch(endEpoch uint64) (*FeeSweep, error) {
+	var result FeeSweep
+	err := db.Where("end_epoch = ?", endEpoch).First(&result).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	ethcrypto "github.com/ethereum/go-ethereum/crypto"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+)
+
+// Methods a recipient of a distribution report is paid by
+const (
+	// MethodTransfer is a recipient paid by the Multisend transfer of a distributeRewards action
+	MethodTransfer = "transfer"
+	// MethodAutoDeposit is a recipient paid by a deposit to its auto deposit bucket
+	MethodAutoDeposit = "autoDeposit"
+)
+
+// ReportCmd is the report command
+var ReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Generate and sign the distribution report of a committed window",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+
+		stored, err := SaveDistributionReport(context.Background(), cfg, c, reportEndEpoch)
+		if err != nil {
+			return err
+		}
+		if reportOutput == "csv" {
+			r := &DistributionReport{}
+			if err := json.Unmarshal([]byte(stored.Content), r); err != nil {
+				return err
+			}
+			return r.WriteCSV(cmd.OutOrStdout())
+		}
+		if reportOutput != "json" {
+			return fmt.Errorf("unknown output format %s", reportOutput)
+		}
+		_, err = io.WriteString(cmd.OutOrStdout(), stored.Content)
+		return err
+	},
+}
+
+var reportVerifyCmd = &cobra.Command{
+	Use:   "verify FILE",
+	Short: "Verify the signature in FILE.sig of a distribution report and print its signer",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		data, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+		signature, err := ioutil.ReadFile(args[0] + ".sig")
+		if err != nil {
+			return err
+		}
+		signer, err := VerifyReport(data, strings.TrimSpace(string(signature)))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), signer.String())
+		return nil
+	},
+}
+
+var (
+	reportEndEpoch uint64
+	reportOutput   string
+)
+
+func init() {
+	ReportCmd.Flags().Uint64Var(&reportEndEpoch, "end-epoch", 0, "end epoch of the window")
+	ReportCmd.Flags().StringVarP(&reportOutput, "output", "o", "json", "output format, json or csv")
+	ReportCmd.MarkFlagRequired("end-epoch")
+	ReportCmd.AddCommand(reportVerifyCmd)
+}
+
+// DistributionReport is the proof of what the recipients of a committed window received
+type DistributionReport struct {
+	EndEpoch  uint64               `json:"endEpoch"`
+	PlanHash  string               `json:"planHash"`
+	Committed bool                 `json:"committed"`
+	Vault     string               `json:"vault"`
+	Delegates []*DelegateStatement `json:"delegates"`
+}
+
+// DelegateStatement is the distribution of a delegate in a distribution report
+type DelegateStatement struct {
+	DelegateName string                `json:"delegateName"`
+	StartEpoch   uint64                `json:"startEpoch"`
+	EndEpoch     uint64                `json:"endEpoch"`
+	ServiceFee   string                `json:"serviceFee"`
+	Refund       string                `json:"refund"`
+	Recipients   []*RecipientStatement `json:"recipients"`
+}
+
+// RecipientStatement is the payment of a recipient in a distribution report. PaidTo is the address the transfer was
+// sent to, which differs from Recipient if it is forwarded, Unpaid marks a recipient whose payment is found at neither,
+// and DepositHash the action of an auto deposit, empty until it is sent.
+type RecipientStatement struct {
+	Recipient     string `json:"recipient"`
+	Amount        string `json:"amount"`
+	Method        string `json:"method"`
+	PaidTo        string `json:"paidTo"`
+	ActionHash    string `json:"actionHash"`
+	Unpaid        bool   `json:"unpaid,omitempty"`
+	DepositStatus string `json:"depositStatus,omitempty"`
+	DepositHash   string `json:"depositHash,omitempty"`
+}
+
+// NewDistributionReport returns the report of the window ending at endEpoch from its persisted plan, the payments of
+// its distributeRewards actions on chain, matched to the recipients by destination, and its drop records
+func NewDistributionReport(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) (*DistributionReport, error) {
+	stored, err := dao.FindPlanByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("no distribution plan of end epoch %d", endEpoch)
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal([]byte(stored.Content), plan); err != nil {
+		return nil, errors.Wrap(err, "failed to parse distribution plan")
+	}
+	records, err := dao.FindDropRecordsByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	payments, _, committed, err := scanPayments(ctx, cfg, c, endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	deposits := make(map[string]*dao.DropRecord)
+	for i := range records {
+		deposits[records[i].DelegateName+","+records[i].Voter] = &records[i]
+	}
+
+	r := &DistributionReport{
+		EndEpoch:  endEpoch,
+		PlanHash:  stored.Hash,
+		Committed: committed,
+		Vault:     c.Account().Address().String(),
+	}
+	m := newPaymentMatcher(cfg, c, endEpoch)
+	for _, d := range plan.Delegates {
+		statement := &DelegateStatement{
+			DelegateName: d.DelegateName,
+			StartEpoch:   d.StartEpoch,
+			EndEpoch:     endEpoch,
+			ServiceFee:   d.ServiceFee,
+			Refund:       d.Refund,
+			Recipients:   make([]*RecipientStatement, 0, len(d.Recipients)),
+		}
+		m.reset(payments[d.DelegateName])
+		for i, recipient := range d.Recipients {
+			addr := common.HexToAddress(recipient)
+			ioAddr, err := address.FromBytes(addr.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			s := &RecipientStatement{Recipient: ioAddr.String(), Amount: d.Amounts[i], Method: MethodTransfer}
+			// an auto deposit voter is paid zero on chain and gets the amount from its drop record
+			expected, ok := new(big.Int).SetString(d.Amounts[i], 10)
+			if !ok {
+				return nil, errors.New("failed to convert string to big int")
+			}
+			if record, ok := deposits[d.DelegateName+","+ioAddr.String()]; ok {
+				s.Method, s.DepositStatus, s.DepositHash = MethodAutoDeposit, record.Status, record.Hash
+				expected = big.NewInt(0)
+			}
+			target, err := m.target(ctx, addr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read forward address of %s", ioAddr.String())
+			}
+			if p := m.take(target, expected); p != nil {
+				to, err := address.FromBytes(p.to.Bytes())
+				if err != nil {
+					return nil, err
+				}
+				s.PaidTo, s.ActionHash = to.String(), p.actionHash
+			} else {
+				s.Unpaid = true
+			}
+			statement.Recipients = append(statement.Recipients, s)
+		}
+		r.Delegates = append(r.Delegates, statement)
+	}
+	return r, nil
+}
+
+// SaveDistributionReport generates the report of the window ending at endEpoch, signs it with the vault account and
+// saves it to the database, replacing an earlier report of the window, and to the reports directory if it is set
+func SaveDistributionReport(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) (*dao.Report, error) {
+	r, err := NewDistributionReport(ctx, cfg, c, endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	var content bytes.Buffer
+	if err := r.WriteJSON(&content); err != nil {
+		return nil, err
+	}
+	signature, err := signReport(c, content.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	stored := &dao.Report{
+		EndEpoch:  endEpoch,
+		Content:   content.String(),
+		Signature: signature,
+		Signer:    r.Vault,
+	}
+	if err := stored.Save(nil); err != nil {
+		return nil, errors.Wrap(err, "failed to save distribution report")
+	}
+
+	if cfg.Reports.Dir != "" {
+		var rows bytes.Buffer
+		if err := r.WriteCSV(&rows); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(cfg.Reports.Dir, 0755); err != nil {
+			return nil, err
+		}
+		for ext, data := range map[string][]byte{"json": content.Bytes(), "csv": rows.Bytes()} {
+			path := filepath.Join(cfg.Reports.Dir, fmt.Sprintf("%d.%s", endEpoch, ext))
+			if err := writeSignedFile(c, path, data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	logger.Ctx(ctx).Info("saved distribution report", zap.Bool("committed", r.Committed))
+	return stored, nil
+}
+
+// writeSignedFile writes data to path and its signature by the account of c to path.sig
+func writeSignedFile(c chain.Client, path string, data []byte) error {
+	signature, err := signReport(c, data)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+".sig", []byte(signature+"\n"), 0644)
+}
+
+// signReport returns the hex encoded signature of data by the account of c
+func signReport(c chain.Client, data []byte) (string, error) {
+	signature, err := c.Account().Sign(data)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign distribution report")
+	}
+	return hex.EncodeToString(signature), nil
+}
+
+// VerifyReport returns the address which signed data into the hex encoded signature, the secp256k1 signature of the
+// Keccak-256 hash of data
+func VerifyReport(data []byte, signature string) (address.Address, error) {
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature")
+	}
+	h := hash.Hash256b(data)
+	pub, err := ethcrypto.SigToPub(h[:], sig)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid signature")
+	}
+	return address.FromBytes(ethcrypto.PubkeyToAddress(*pub).Bytes())
+}
+
+// WriteJSON writes the distribution report as indented JSON
+func (r *DistributionReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes the distribution report as CSV, a row per recipient of every delegate
+func (r *DistributionReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"startEpoch", "endEpoch", "delegateName", "serviceFee", "refund", "recipient", "amount", "method",
+		"paidTo", "actionHash", "unpaid", "depositStatus", "depositHash"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, d := range r.Delegates {
+		for _, s := range d.Recipients {
+			row := []string{strconv.FormatUint(d.StartEpoch, 10), strconv.FormatUint(d.EndEpoch, 10), d.DelegateName,
+				d.ServiceFee, d.Refund, s.Recipient, s.Amount, s.Method, s.PaidTo, s.ActionHash, strconv.FormatBool(s.Unpaid), s.DepositStatus, s.DepositHash}
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestDistributionReport(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+	dir, err := ioutil.TempDir("", "reports")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100000"},
+			{Voter: testAddress(2).String(), Amount: "200000"},
+		},
+	})
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.ChunkSize = 1
+	cfg.Reports.Dir = dir
+	hermes := newFakeHermes(cfg, c)
+	hermes.buckets[common.BytesToAddress(testAddress(1).Bytes())] = 7
+	ctx := context.Background()
+
+	// the report is saved on commit, with the auto deposit not sent yet
+	require.NoError(Reward(ctx, cfg, c, nil))
+	stored, err := dao.FindReportByEndEpoch(24)
+	require.NoError(err)
+	require.NotNil(stored)
+	r := &DistributionReport{}
+	require.NoError(json.Unmarshal([]byte(stored.Content), r))
+	require.True(r.Committed)
+	require.Equal(c.Account().Address().String(), r.Vault)
+	require.Len(r.Delegates, 1)
+	recipients := r.Delegates[0].Recipients
+	require.Len(recipients, 2)
+	require.Equal(MethodTransfer, recipients[0].Method)
+	// the recipients are sorted by amount
+	require.Equal(testAddress(2).String(), recipients[0].PaidTo)
+	require.NotEmpty(recipients[0].ActionHash)
+	require.Equal(MethodAutoDeposit, recipients[1].Method)
+	require.Equal("new", recipients[1].DepositStatus)
+	require.NotEqual(recipients[0].ActionHash, recipients[1].ActionHash)
+
+	// the report saved again after the deposits are sent replaces the first one
+	NewSender(cfg, c).Send(ctx)
+	_, err = SaveDistributionReport(ctx, cfg, c, 24)
+	require.NoError(err)
+	stored, err = dao.FindReportByEndEpoch(24)
+	require.NoError(err)
+	require.NoError(json.Unmarshal([]byte(stored.Content), r))
+	require.Equal("completed", r.Delegates[0].Recipients[1].DepositStatus)
+	require.NotEmpty(r.Delegates[0].Recipients[1].DepositHash)
+
+	signer, err := VerifyReport([]byte(stored.Content), stored.Signature)
+	require.NoError(err)
+	require.Equal(c.Account().Address().String(), signer.String())
+	for _, name := range []string{"24.json", "24.csv"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		require.NoError(err)
+		signature, err := ioutil.ReadFile(filepath.Join(dir, name+".sig"))
+		require.NoError(err)
+		signer, err := VerifyReport(data, strings.TrimSpace(string(signature)))
+		require.NoError(err)
+		require.Equal(c.Account().Address().String(), signer.String())
+
+		// a tampered report is not signed by the vault
+		signer, err = VerifyReport(append(data, ' '), strings.TrimSpace(string(signature)))
+		if err == nil {
+			require.NotEqual(c.Account().Address().String(), signer.String())
+		}
+	}
+}
+
+func TestDistributionReportOutOfLine(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100000"},
+			{Voter: testAddress(2).String(), Amount: "200000"},
+			{Voter: testAddress(3).String(), Amount: "300000"},
+		},
+	})
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.ChunkSize = 1
+	hermes := newFakeHermes(cfg, c)
+	ctx := context.Background()
+
+	window, err := getDistribution(ctx, cfg, c)
+	require.NoError(err)
+	plan, err := loadPlan(ctx, cfg, c, window, false)
+	require.NoError(err)
+	d := plan.Delegates[0]
+	// the last recipient forwards its reward
+	last := common.HexToAddress(d.Recipients[2])
+	hermes.forwards[last] = &ForwardService{Nonce: big.NewInt(1),
+		Destination: common.BytesToAddress(testAddress(20).Bytes()), StartEpoch: big.NewInt(0)}
+
+	// the second chunk is mined twice and before the first one
+	var hashes []string
+	for _, i := range []int{1, 1, 0} {
+		amount, ok := new(big.Int).SetString(d.Amounts[i], 10)
+		require.True(ok)
+		require.NoError(sendRewards(ctx, cfg, c, "alpha", window.endEpoch, window.minTips,
+			[]common.Address{common.HexToAddress(d.Recipients[i])}, []*big.Int{amount}))
+		actions := c.Actions()
+		hashes = append(hashes, hex.EncodeToString(actions[len(actions)-1].Hash[:]))
+	}
+	hermes.distributedCount[stringToBytes32("alpha")] = 2
+	require.NoError(Reward(ctx, cfg, c, nil))
+
+	r, err := NewDistributionReport(ctx, cfg, c, 24)
+	require.NoError(err)
+	recipients := r.Delegates[0].Recipients
+	require.Len(recipients, 3)
+	for i, s := range recipients {
+		addr, err := evmAddrToIoAddr(common.HexToAddress(d.Recipients[i]))
+		require.NoError(err)
+		require.Equal(addr, s.Recipient)
+		require.False(s.Unpaid)
+		if i < 2 {
+			require.Equal(addr, s.PaidTo)
+		}
+	}
+	require.Equal(hashes[2], recipients[0].ActionHash)
+	require.Equal(hashes[0], recipients[1].ActionHash)
+	require.Equal(testAddress(20).String(), recipients[2].PaidTo)
+
+	// the payment of a recipient to an address it does not forward to leaves it unpaid
+	delete(hermes.forwards, last)
+	r, err = NewDistributionReport(ctx, cfg, c, 24)
+	require.NoError(err)
+	recipients = r.Delegates[0].Recipients
+	require.True(recipients[2].Unpaid)
+	require.Empty(recipients[2].PaidTo)
+	require.Empty(recipients[2].ActionHash)
+}
@@ -0,0 +1,449 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shurcooL/graphql"
+	"github.com/spf13/cobra"
+
+	"github.com/iotexproject/iotex-hermes/cmd/key"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// Bookkeeping sources
+const (
+	BookkeepingSourceGraphQL = "graphql"
+	BookkeepingSourceFile    = "file"
+)
+
+// csvHeader is the header of a CSV bookkeeping file, which has a row per voter reward of a delegate
+var csvHeader = []string{
+	"start_epoch", "epoch_count", "reward_address", "delegate_name", "staking_address", "voter_count",
+	"waive_service_fee", "refund", "voter", "amount",
+}
+
+type (
+	// BookkeepingSource provides the rewards of the delegates within a distribution window
+	BookkeepingSource interface {
+		Bookkeeping(ctx context.Context, startEpoch uint64, epochCount uint64, rewardAddress string) (*Bookkeeping, error)
+		// Ping returns an error if the source cannot be reached
+		Ping(ctx context.Context) error
+	}
+
+	// Bookkeeping is the rewards of the delegates paying to rewardAddress within a distribution window
+	Bookkeeping struct {
+		StartEpoch    uint64                 `json:"startEpoch"`
+		EpochCount    uint64                 `json:"epochCount"`
+		RewardAddress string                 `json:"rewardAddress"`
+		Delegates     []*DelegateBookkeeping `json:"delegates"`
+	}
+
+	// DelegateBookkeeping is the rewards of the voters of a delegate, amounts are decimal strings in Rau
+	DelegateBookkeeping struct {
+		DelegateName    string         `json:"delegateName"`
+		StakingAddress  string         `json:"stakingAddress"`
+		VoterCount      int64          `json:"voterCount"`
+		WaiveServiceFee bool           `json:"waiveServiceFee"`
+		Refund          string         `json:"refund"`
+		Rewards         []*VoterReward `json:"rewards"`
+	}
+
+	// VoterReward is the reward of a voter
+	VoterReward struct {
+		Voter  string `json:"voter"`
+		Amount string `json:"amount"`
+	}
+
+	graphqlSource struct {
+		endpoint        string
+		waiverThreshold int
+	}
+
+	fileSource struct {
+		path      string
+		publicKey string
+	}
+)
+
+// BookkeepingCmd is the bookkeeping command
+var BookkeepingCmd = &cobra.Command{
+	Use:   "bookkeeping",
+	Short: "Export and sign bookkeeping files",
+}
+
+var bookkeepingExportCmd = &cobra.Command{
+	Use:   "export FILE",
+	Short: "Export the bookkeeping of a window from the analytics endpoint to a JSON or CSV file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.LoadAnalytics(config.File)
+		if err != nil {
+			return err
+		}
+		source := NewGraphQLSource(cfg.AnalyticsEndpoint, cfg.Distribution.WaiverThreshold)
+		return ExportBookkeeping(context.Background(), source, exportStartEpoch, exportEpochCount, cfg.Vault.Address, args[0])
+	},
+}
+
+var bookkeepingSignCmd = &cobra.Command{
+	Use:   "sign FILE",
+	Short: "Sign a bookkeeping file into FILE.sig",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		privateKey, err := ioutil.ReadFile(signKeyFile)
+		if err != nil {
+			return err
+		}
+		return SignBookkeepingFile(args[0], strings.TrimSpace(string(privateKey)))
+	},
+}
+
+var (
+	exportStartEpoch uint64
+	exportEpochCount uint64
+	signKeyFile      string
+)
+
+func init() {
+	bookkeepingExportCmd.Flags().Uint64Var(&exportStartEpoch, "start-epoch", 0, "start epoch of the window")
+	bookkeepingExportCmd.Flags().Uint64Var(&exportEpochCount, "epoch-count", 24, "number of epochs of the window")
+	bookkeepingExportCmd.MarkFlagRequired("start-epoch")
+	bookkeepingSignCmd.Flags().StringVar(&signKeyFile, "key-file", "", "file of the base64 encoded PKCS8 RSA private key")
+	bookkeepingSignCmd.MarkFlagRequired("key-file")
+	BookkeepingCmd.AddCommand(bookkeepingExportCmd)
+	BookkeepingCmd.AddCommand(bookkeepingSignCmd)
+}
+
+// NewBookkeepingSource returns the bookkeeping source of cfg
+func NewBookkeepingSource(cfg *config.Config) (BookkeepingSource, error) {
+	switch cfg.Bookkeeping.Source {
+	case BookkeepingSourceGraphQL:
+		return NewGraphQLSource(cfg.AnalyticsEndpoint, cfg.Distribution.WaiverThreshold), nil
+	case BookkeepingSourceFile:
+		return NewFileSource(cfg.Bookkeeping.File, cfg.Bookkeeping.PublicKey), nil
+	default:
+		return nil, fmt.Errorf("unknown bookkeeping source %s", cfg.Bookkeeping.Source)
+	}
+}
+
+// NewGraphQLSource returns a source querying the hermes bookkeeping of the analytics endpoint
+func NewGraphQLSource(endpoint string, waiverThreshold int) BookkeepingSource {
+	return &graphqlSource{endpoint: endpoint, waiverThreshold: waiverThreshold}
+}
+
+// NewFileSource returns a source reading a JSON or CSV bookkeeping file at path, which must be signed by the RSA
+// key publicKey in path.sig
+func NewFileSource(path string, publicKey string) BookkeepingSource {
+	return &fileSource{path: path, publicKey: publicKey}
+}
+
+func (s *graphqlSource) Bookkeeping(ctx context.Context, startEpoch uint64, epochCount uint64, rewardAddress string) (*Bookkeeping, error) {
+	type query struct {
+		Hermes struct {
+			Exist              graphql.Boolean
+			HermesDistribution []struct {
+				DelegateName       graphql.String
+				RewardDistribution []struct {
+					VoterIotexAddress graphql.String
+					Amount            graphql.String
+				}
+				StakingIotexAddress graphql.String
+				VoterCount          graphql.Int
+				WaiveServiceFee     graphql.Boolean
+				Refund              graphql.String
+			}
+		} `graphql:"hermes(startEpoch: $startEpoch, epochCount: $epochCount, rewardAddress: $rewardAddress, waiverThreshold: $waiverThreshold)"`
+	}
+
+	gqlClient := graphql.NewClient(s.endpoint, nil)
+
+	// make sure every epoch does not miss hermes info
+	for epoch := startEpoch; epoch < startEpoch+epochCount; epoch++ {
+		tempVariables := map[string]interface{}{
+			"startEpoch":      graphql.Int(epoch),
+			"epochCount":      graphql.Int(1),
+			"rewardAddress":   graphql.String(rewardAddress),
+			"waiverThreshold": graphql.Int(s.waiverThreshold),
+		}
+		var tempOutput query
+		if err := gqlClient.Query(ctx, &tempOutput, tempVariables); err != nil {
+			return nil, err
+		}
+		if !tempOutput.Hermes.Exist {
+			return nil, errors.New(fmt.Sprintf("bookkeeping info doesn't exist for Epoch %d\n", epoch))
+		}
+	}
+
+	variables := map[string]interface{}{
+		"startEpoch":      graphql.Int(startEpoch),
+		"epochCount":      graphql.Int(epochCount),
+		"rewardAddress":   graphql.String(rewardAddress),
+		"waiverThreshold": graphql.Int(s.waiverThreshold),
+	}
+	var output query
+	if err := gqlClient.Query(ctx, &output, variables); err != nil {
+		return nil, err
+	}
+
+	if !output.Hermes.Exist {
+		return nil, errors.New("bookkeeping info doesn't exist within the epoch range")
+	}
+
+	bookkeeping := &Bookkeeping{
+		StartEpoch:    startEpoch,
+		EpochCount:    epochCount,
+		RewardAddress: rewardAddress,
+	}
+	for _, hermesDistribution := range output.Hermes.HermesDistribution {
+		delegate := &DelegateBookkeeping{
+			DelegateName:    string(hermesDistribution.DelegateName),
+			StakingAddress:  string(hermesDistribution.StakingIotexAddress),
+			VoterCount:      int64(hermesDistribution.VoterCount),
+			WaiveServiceFee: bool(hermesDistribution.WaiveServiceFee),
+			Refund:          string(hermesDistribution.Refund),
+		}
+		for _, rewardDistribution := range hermesDistribution.RewardDistribution {
+			delegate.Rewards = append(delegate.Rewards, &VoterReward{
+				Voter:  string(rewardDistribution.VoterIotexAddress),
+				Amount: string(rewardDistribution.Amount),
+			})
+		}
+		bookkeeping.Delegates = append(bookkeeping.Delegates, delegate)
+	}
+	return bookkeeping, nil
+}
+
+func (s *graphqlSource) Ping(ctx context.Context) error {
+	req, err := http.NewRequest(http.MethodGet, s.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "failed to reach analytics endpoint")
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("analytics endpoint responds with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *fileSource) Bookkeeping(ctx context.Context, startEpoch uint64, epochCount uint64, rewardAddress string) (*Bookkeeping, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bookkeeping file")
+	}
+	signature, err := ioutil.ReadFile(s.path + ".sig")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read bookkeeping signature")
+	}
+	pub, err := key.LoadPublicKey(s.publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load bookkeeping public key")
+	}
+	if err := key.Verify(string(data), strings.TrimSpace(string(signature)), pub); err != nil {
+		return nil, errors.Wrapf(err, "invalid signature of bookkeeping file %s", s.path)
+	}
+
+	var bookkeeping *Bookkeeping
+	switch strings.ToLower(filepath.Ext(s.path)) {
+	case ".json":
+		bookkeeping = &Bookkeeping{}
+		err = json.Unmarshal(data, bookkeeping)
+	case ".csv":
+		bookkeeping, err = ReadBookkeepingCSV(strings.NewReader(string(data)))
+	default:
+		return nil, fmt.Errorf("unknown bookkeeping file format %s", s.path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse bookkeeping file %s", s.path)
+	}
+
+	if bookkeeping.StartEpoch != startEpoch || bookkeeping.EpochCount != epochCount ||
+		bookkeeping.RewardAddress != rewardAddress {
+		return nil, fmt.Errorf("bookkeeping file is of start epoch %d, epoch count %d, reward address %s, "+
+			"expected start epoch %d, epoch count %d, reward address %s", bookkeeping.StartEpoch, bookkeeping.EpochCount,
+			bookkeeping.RewardAddress, startEpoch, epochCount, rewardAddress)
+	}
+	return bookkeeping, nil
+}
+
+func (s *fileSource) Ping(ctx context.Context) error {
+	for _, path := range []string{s.path, s.path + ".sig"} {
+		if _, err := os.Stat(path); err != nil {
+			return errors.Wrap(err, "failed to stat bookkeeping file")
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the bookkeeping as indented JSON
+func (b *Bookkeeping) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(b)
+}
+
+// WriteCSV writes the bookkeeping as CSV, a delegate without any voter reward has a row with empty voter and amount
+func (b *Bookkeeping) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, d := range b.Delegates {
+		row := func(voter, amount string) []string {
+			return []string{
+				strconv.FormatUint(b.StartEpoch, 10), strconv.FormatUint(b.EpochCount, 10), b.RewardAddress,
+				d.DelegateName, d.StakingAddress, strconv.FormatInt(d.VoterCount, 10),
+				strconv.FormatBool(d.WaiveServiceFee), d.Refund, voter, amount,
+			}
+		}
+		if len(d.Rewards) == 0 {
+			if err := writer.Write(row("", "")); err != nil {
+				return err
+			}
+		}
+		for _, r := range d.Rewards {
+			if err := writer.Write(row(r.Voter, r.Amount)); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadBookkeepingCSV reads the bookkeeping written by WriteCSV
+func ReadBookkeepingCSV(r io.Reader) (*Bookkeeping, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(csvHeader)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(csvHeader, ",") {
+		return nil, errors.New("invalid csv header")
+	}
+
+	var bookkeeping *Bookkeeping
+	delegates := make(map[string]*DelegateBookkeeping)
+	for i, row := range rows[1:] {
+		startEpoch, err := strconv.ParseUint(row[0], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid start epoch in line %d", i+2)
+		}
+		epochCount, err := strconv.ParseUint(row[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid epoch count in line %d", i+2)
+		}
+		if bookkeeping == nil {
+			bookkeeping = &Bookkeeping{StartEpoch: startEpoch, EpochCount: epochCount, RewardAddress: row[2]}
+		} else if bookkeeping.StartEpoch != startEpoch || bookkeeping.EpochCount != epochCount ||
+			bookkeeping.RewardAddress != row[2] {
+			return nil, fmt.Errorf("window of line %d differs from the previous lines", i+2)
+		}
+		voterCount, err := strconv.ParseInt(row[5], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid voter count in line %d", i+2)
+		}
+		waiveServiceFee, err := strconv.ParseBool(row[6])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid waive service fee in line %d", i+2)
+		}
+		d, ok := delegates[row[3]]
+		if !ok {
+			d = &DelegateBookkeeping{
+				DelegateName:    row[3],
+				StakingAddress:  row[4],
+				VoterCount:      voterCount,
+				WaiveServiceFee: waiveServiceFee,
+				Refund:          row[7],
+			}
+			delegates[row[3]] = d
+			bookkeeping.Delegates = append(bookkeeping.Delegates, d)
+		} else if d.StakingAddress != row[4] || d.VoterCount != voterCount || d.WaiveServiceFee != waiveServiceFee ||
+			d.Refund != row[7] {
+			return nil, fmt.Errorf("delegate %s of line %d differs from the previous lines", row[3], i+2)
+		}
+		if row[8] != "" {
+			d.Rewards = append(d.Rewards, &VoterReward{Voter: row[8], Amount: row[9]})
+		}
+	}
+	if bookkeeping == nil {
+		return nil, errors.New("empty bookkeeping")
+	}
+	return bookkeeping, nil
+}
+
+// ExportBookkeeping writes the bookkeeping of a window from source to the JSON or CSV file at path, which is only
+// replaced once the whole bookkeeping is written
+func ExportBookkeeping(ctx context.Context, source BookkeepingSource, startEpoch uint64, epochCount uint64, rewardAddress string, path string) error {
+	var write func(*Bookkeeping, io.Writer) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		write = (*Bookkeeping).WriteJSON
+	case ".csv":
+		write = (*Bookkeeping).WriteCSV
+	default:
+		return fmt.Errorf("unknown bookkeeping file format %s", path)
+	}
+	bookkeeping, err := source.Bookkeeping(ctx, startEpoch, epochCount, rewardAddress)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if err := write(bookkeeping, f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return errors.Wrapf(err, "failed to write bookkeeping file %s", path)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// SignBookkeepingFile signs the file at path with the RSA key privateKey into path.sig
+func SignBookkeepingFile(path string, privateKey string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	priv, err := key.LoadPrivateKey(privateKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to load private key")
+	}
+	signature, err := key.Sign(string(data), priv)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+".sig", []byte(signature+"\n"), 0644)
+}
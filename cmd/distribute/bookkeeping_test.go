@@ -0,0 +1,204 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestFileSource(t *testing.T) {
+	require := require.New(t)
+
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(err)
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(err)
+	pubDER, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	require.NoError(err)
+	privateKey := base64.StdEncoding.EncodeToString(privDER)
+	publicKey := base64.StdEncoding.EncodeToString(pubDER)
+
+	dir, err := ioutil.TempDir("", "bookkeeping")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+
+	rewardAddress := testAddress(9).String()
+	expected := &Bookkeeping{
+		StartEpoch:    25,
+		EpochCount:    24,
+		RewardAddress: rewardAddress,
+		Delegates: []*DelegateBookkeeping{
+			{
+				DelegateName:   "alpha",
+				StakingAddress: testAddress(1).String(),
+				VoterCount:     2,
+				Refund:         "1000",
+				Rewards: []*VoterReward{
+					{Voter: testAddress(1).String(), Amount: "100"},
+					{Voter: testAddress(2).String(), Amount: "200"},
+				},
+			},
+			{
+				DelegateName:    "beta",
+				StakingAddress:  testAddress(3).String(),
+				WaiveServiceFee: true,
+				Refund:          "0",
+			},
+		},
+	}
+
+	for _, name := range []string{"bookkeeping.json", "bookkeeping.csv"} {
+		path := filepath.Join(dir, name)
+		f, err := os.Create(path)
+		require.NoError(err)
+		if filepath.Ext(name) == ".json" {
+			require.NoError(expected.WriteJSON(f))
+		} else {
+			require.NoError(expected.WriteCSV(f))
+		}
+		require.NoError(f.Close())
+
+		source := NewFileSource(path, publicKey)
+		_, err = source.Bookkeeping(context.Background(), 25, 24, rewardAddress)
+		require.Error(err, "unsigned file")
+		require.Error(source.Ping(context.Background()))
+
+		require.NoError(SignBookkeepingFile(path, privateKey))
+		require.NoError(source.Ping(context.Background()))
+		bookkeeping, err := source.Bookkeeping(context.Background(), 25, 24, rewardAddress)
+		require.NoError(err)
+		require.Equal(expected, bookkeeping)
+
+		_, err = source.Bookkeeping(context.Background(), 49, 24, rewardAddress)
+		require.Error(err, "window mismatch")
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(err)
+		require.NoError(ioutil.WriteFile(path, append(data, '\n'), 0644))
+		_, err = source.Bookkeeping(context.Background(), 25, 24, rewardAddress)
+		require.Error(err, "tampered file")
+	}
+}
+
+func TestGraphQLSource(t *testing.T) {
+	require := require.New(t)
+
+	rewardAddress := testAddress(9).String()
+	fixture := &mockanalytics.Fixture{}
+	for epoch := uint64(25); epoch < 27; epoch++ {
+		fixture.Epochs = append(fixture.Epochs, &mockanalytics.Epoch{
+			Epoch:         epoch,
+			RewardAddress: rewardAddress,
+			Delegates: []*mockanalytics.Delegate{
+				{
+					DelegateName:   "alpha",
+					StakingAddress: testAddress(1).String(),
+					Refund:         "500",
+					Rewards: []*mockanalytics.Reward{
+						{Voter: testAddress(1).String(), Amount: "100"},
+						{Voter: testAddress(2).String(), Amount: "200"},
+					},
+				},
+				{
+					DelegateName:   "beta",
+					StakingAddress: testAddress(3).String(),
+					Refund:         "0",
+					Rewards:        []*mockanalytics.Reward{{Voter: testAddress(3).String(), Amount: "300"}},
+				},
+			},
+		})
+	}
+	server := httptest.NewServer(mockanalytics.NewServer(fixture))
+	defer server.Close()
+	source := NewGraphQLSource(server.URL, 2)
+	require.NoError(source.Ping(context.Background()))
+
+	bookkeeping, err := source.Bookkeeping(context.Background(), 25, 2, rewardAddress)
+	require.NoError(err)
+	require.Equal(&Bookkeeping{
+		StartEpoch:    25,
+		EpochCount:    2,
+		RewardAddress: rewardAddress,
+		Delegates: []*DelegateBookkeeping{
+			{
+				DelegateName:    "alpha",
+				StakingAddress:  testAddress(1).String(),
+				VoterCount:      2,
+				WaiveServiceFee: true,
+				Refund:          "1000",
+				// sorted by address
+				Rewards: []*VoterReward{
+					{Voter: testAddress(2).String(), Amount: "400"},
+					{Voter: testAddress(1).String(), Amount: "200"},
+				},
+			},
+			{
+				DelegateName:   "beta",
+				StakingAddress: testAddress(3).String(),
+				VoterCount:     1,
+				Refund:         "0",
+				Rewards:        []*VoterReward{{Voter: testAddress(3).String(), Amount: "600"}},
+			},
+		},
+	}, bookkeeping)
+
+	// the window misses epoch 27
+	_, err = source.Bookkeeping(context.Background(), 25, 3, rewardAddress)
+	require.Error(err)
+	// the bookkeeping is of another reward address
+	_, err = source.Bookkeeping(context.Background(), 25, 2, testAddress(8).String())
+	require.Error(err)
+
+	dir, err := ioutil.TempDir("", "bookkeeping")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	jsonPath := filepath.Join(dir, "bookkeeping.json")
+	csvPath := filepath.Join(dir, "bookkeeping.csv")
+	require.NoError(ExportBookkeeping(context.Background(), source, 25, 2, rewardAddress, jsonPath))
+	require.NoError(ExportBookkeeping(context.Background(), source, 25, 2, rewardAddress, csvPath))
+	data, err := ioutil.ReadFile(jsonPath)
+	require.NoError(err)
+	exported := &Bookkeeping{}
+	require.NoError(json.Unmarshal(data, exported))
+	require.Equal(bookkeeping, exported)
+	f, err := os.Open(csvPath)
+	require.NoError(err)
+	exported, err = ReadBookkeepingCSV(f)
+	require.NoError(f.Close())
+	require.NoError(err)
+	require.Equal(bookkeeping, exported)
+
+	// an unknown format is rejected before anything is created, and a failed fetch keeps the previous file
+	txtPath := filepath.Join(dir, "bookkeeping.txt")
+	require.Error(ExportBookkeeping(context.Background(), source, 25, 2, rewardAddress, txtPath))
+	_, err = os.Stat(txtPath)
+	require.True(os.IsNotExist(err))
+	require.Error(ExportBookkeeping(context.Background(), source, 25, 3, rewardAddress, jsonPath))
+	after, err := ioutil.ReadFile(jsonPath)
+	require.NoError(err)
+	require.Equal(data, after)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(err)
+	require.Len(files, 2)
+
+	server.Close()
+	require.Error(source.Ping(context.Background()))
+}
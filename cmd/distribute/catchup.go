@@ -0,0 +1,110 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// CatchUpPlan is the windows owed since the last committed one
+type CatchUpPlan struct {
+	CurrentEpoch uint64        `json:"currentEpoch"`
+	Windows      []*WindowPlan `json:"windows"`
+}
+
+// WindowPlan is the bookkeeping summary of an owed window, the amount is the rewards and refunds before service fees
+type WindowPlan struct {
+	StartEpoch  uint64 `json:"startEpoch"`
+	EndEpoch    uint64 `json:"endEpoch"`
+	Delegates   int    `json:"delegates"`
+	Recipients  int    `json:"recipients"`
+	TotalAmount string `json:"totalAmount"`
+}
+
+// PlanCatchUp returns the plan of the owed windows, at most max windows if max is positive
+func PlanCatchUp(ctx context.Context, cfg *config.Config, c chain.Client, max int) (*CatchUpPlan, error) {
+	windows, err := OwedWindows(ctx, cfg, c, max)
+	if err != nil {
+		return nil, err
+	}
+	plan := &CatchUpPlan{}
+	if len(windows) == 0 {
+		window, err := NextWindow(ctx, cfg, c)
+		if err != nil {
+			return nil, err
+		}
+		plan.CurrentEpoch = window.CurrentEpoch
+		return plan, nil
+	}
+	plan.CurrentEpoch = windows[0].CurrentEpoch
+
+	source, err := NewBookkeepingSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rewardAddress := c.Account().Address().String()
+	for _, window := range windows {
+		bookkeeping, err := source.Bookkeeping(ctx, window.StartEpoch, window.EndEpoch-window.StartEpoch+1, rewardAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get bookkeeping of epochs %d to %d", window.StartEpoch, window.EndEpoch)
+		}
+		total := big.NewInt(0)
+		recipients := 0
+		for _, d := range bookkeeping.Delegates {
+			refund, ok := new(big.Int).SetString(d.Refund, 10)
+			if !ok {
+				return nil, errors.New("failed to convert string to big int")
+			}
+			total.Add(total, refund)
+			for _, r := range d.Rewards {
+				amount, ok := new(big.Int).SetString(r.Amount, 10)
+				if !ok {
+					return nil, errors.New("failed to convert string to big int")
+				}
+				total.Add(total, amount)
+			}
+			recipients += len(d.Rewards)
+		}
+		plan.Windows = append(plan.Windows, &WindowPlan{
+			StartEpoch:  window.StartEpoch,
+			EndEpoch:    window.EndEpoch,
+			Delegates:   len(bookkeeping.Delegates),
+			Recipients:  recipients,
+			TotalAmount: total.String(),
+		})
+	}
+	return plan, nil
+}
+
+// WriteJSON writes the plan as indented JSON
+func (p *CatchUpPlan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// WriteTable writes the plan as a human readable table
+func (p *CatchUpPlan) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Current Epoch: %d, Owed Windows: %d\n\n", p.CurrentEpoch, len(p.Windows))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "START EPOCH\tEND EPOCH\tDELEGATES\tRECIPIENTS\tTOTAL AMOUNT")
+	for _, window := range p.Windows {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%s\n", window.StartEpoch, window.EndEpoch, window.Delegates, window.Recipients,
+			window.TotalAmount)
+	}
+	return tw.Flush()
+}
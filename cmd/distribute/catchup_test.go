@@ -0,0 +1,92 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestPlanCatchUp(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	voter := testAddress(1)
+	fixture := &mockanalytics.Fixture{}
+	for epoch := uint64(1); epoch <= 72; epoch++ {
+		fixture.Epochs = append(fixture.Epochs, &mockanalytics.Epoch{Epoch: epoch, Delegates: []*mockanalytics.Delegate{
+			{DelegateName: "alpha", StakingAddress: voter.String(), Refund: "1",
+				Rewards: []*mockanalytics.Reward{{Voter: voter.String(), Amount: "10"}}},
+		}})
+	}
+	analytics := httptest.NewServer(mockanalytics.NewServer(fixture))
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Schedule = config.Schedule{WindowEpochs: 24, FinalityLag: 2, EpochDuration: time.Hour}
+	hermes := newFakeHermes(cfg, c)
+
+	// nothing is owed before the first window is final
+	c.SetChainMeta(100, 25)
+	plan, err := PlanCatchUp(context.Background(), cfg, c, 0)
+	require.NoError(err)
+	require.Equal(uint64(25), plan.CurrentEpoch)
+	require.Empty(plan.Windows)
+
+	// three windows are owed, the last one is final
+	c.SetChainMeta(100, 74)
+	plan, err = PlanCatchUp(context.Background(), cfg, c, 0)
+	require.NoError(err)
+	require.Len(plan.Windows, 3)
+	for i, window := range plan.Windows {
+		require.Equal(&WindowPlan{
+			StartEpoch:  uint64(24*i + 1),
+			EndEpoch:    uint64(24*i + 24),
+			Delegates:   1,
+			Recipients:  1,
+			TotalAmount: "264",
+		}, window)
+	}
+	var buf bytes.Buffer
+	require.NoError(plan.WriteJSON(&buf))
+	decoded := &CatchUpPlan{}
+	require.NoError(json.Unmarshal(buf.Bytes(), decoded))
+	require.Equal(plan, decoded)
+	buf.Reset()
+	require.NoError(plan.WriteTable(&buf))
+	require.Contains(buf.String(), "Owed Windows: 3")
+
+	plan, err = PlanCatchUp(context.Background(), cfg, c, 2)
+	require.NoError(err)
+	require.Len(plan.Windows, 2)
+
+	// a bookkeeping file covers one window at a time
+	cfg.Bookkeeping.Source = BookkeepingSourceFile
+	_, err = PlanCatchUp(context.Background(), cfg, c, 0)
+	require.Error(err)
+	require.Contains(err.Error(), "3 windows are owed")
+	cfg.Bookkeeping.Source = BookkeepingSourceGraphQL
+
+	// every window is committed on its own
+	for i := 0; i < 3; i++ {
+		require.NoError(Reward(context.Background(), cfg, c, nil))
+	}
+	require.Equal([]*big.Int{big.NewInt(24), big.NewInt(48), big.NewInt(72)}, hermes.endEpochs)
+	plan, err = PlanCatchUp(context.Background(), cfg, c, 0)
+	require.NoError(err)
+	require.Empty(plan.Windows)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+)
+
+// chunker picks the recipients of the next distributeRewards action of a delegate: as many of the remaining ones as
+// distribution.chunkSize and the Multisend limit allow, shrunk until the estimated gas fits the gas limit
+type chunker struct {
+	cfg       *config.Config
+	c         chain.Client
+	contract  address.Address
+	hermesABI abi.ABI
+	maxSize   int
+	maxGas    uint64
+}
+
+func newChunker(ctx context.Context, cfg *config.Config, c chain.Client) (*chunker, error) {
+	contract, err := address.FromString(cfg.Contracts.Hermes)
+	if err != nil {
+		return nil, err
+	}
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := maxChunkSize(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	return &chunker{
+		cfg:       cfg,
+		c:         c,
+		contract:  contract,
+		hermesABI: hermesABI,
+		maxSize:   maxSize,
+		maxGas:    chain.NewGasEstimator(cfg.Gas).MaxExecution(),
+	}, nil
+}
+
+// next returns the number of the remaining recipients to distribute to in the next action. The estimate of a chunk
+// above the gas limit shrinks it in proportion, assuming the gas grows linearly with the recipients.
+func (ch *chunker) next(
+	ctx context.Context,
+	delegateName string,
+	endEpoch *big.Int,
+	minTips *big.Int,
+	recipients []common.Address,
+	amounts []*big.Int,
+) (int, error) {
+	size := ch.maxSize
+	if size > len(recipients) {
+		size = len(recipients)
+	}
+	if ch.maxGas == 0 {
+		return size, nil
+	}
+	name := stringToBytes32(delegateName)
+	for {
+		value := new(big.Int).Set(minTips)
+		for _, amount := range amounts[:size] {
+			value.Add(value, amount)
+		}
+		estimated, err := ch.c.EstimateExecution(ctx, ch.contract, ch.hermesABI, value, "distributeRewards",
+			name, endEpoch, recipients[:size], amounts[:size])
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to estimate gas of distributeRewards")
+		}
+		if estimated <= ch.maxGas {
+			return size, nil
+		}
+		if size == 1 {
+			return 0, errors.Wrapf(chain.ErrGasLimitExceeded, "%d of a single recipient, maximum %d", estimated, ch.maxGas)
+		}
+		shrunk := int(uint64(size) * ch.maxGas / estimated)
+		if shrunk >= size {
+			shrunk = size - 1
+		}
+		if shrunk < 1 {
+			shrunk = 1
+		}
+		logger.Ctx(ctx).Debug("shrunk chunk to fit gas limit", zap.Int("from", size), zap.Int("to", shrunk),
+			zap.Uint64("estimatedGas", estimated))
+		size = shrunk
+	}
+}
+
+// maxChunkSize returns the most recipients of a distributeRewards action, distribution.chunkSize capped by the
+// Multisend limit
+func maxChunkSize(ctx context.Context, cfg *config.Config, c chain.Client) (int, error) {
+	limit, err := getMultisendLimit(ctx, cfg, c)
+	if err != nil {
+		return 0, err
+	}
+	size := cfg.Distribution.ChunkSize
+	if limit > 0 && limit < uint64(size) {
+		size = int(limit)
+	}
+	return size, nil
+}
+
+// getMultisendLimit returns the most recipients the Multisend contract accepts in a call
+func getMultisendLimit(ctx context.Context, cfg *config.Config, c chain.Client) (uint64, error) {
+	caddr, err := address.FromString(cfg.Contracts.Multisend)
+	if err != nil {
+		return 0, err
+	}
+	multisendABI, err := abi.JSON(strings.NewReader(MultisendABI))
+	if err != nil {
+		return 0, err
+	}
+	data, err := c.ReadContract(ctx, caddr, multisendABI, "limit")
+	if err != nil {
+		return 0, err
+	}
+	var limit *big.Int
+	if err := data.Unmarshal(&limit); err != nil {
+		return 0, err
+	}
+	return limit.Uint64(), nil
+}
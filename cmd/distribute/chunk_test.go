@@ -0,0 +1,85 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestChunker(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	var rewards []*mockanalytics.Reward
+	for i := byte(1); i <= 8; i++ {
+		rewards = append(rewards, &mockanalytics.Reward{Voter: testAddress(i).String(), Amount: "100"})
+	}
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards:        rewards,
+	})
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	c.SetChainMeta(100, 30)
+	cfg.Gas = config.Gas{Multiplier: 1, MaxLimit: 50000}
+	hermes := newFakeHermes(cfg, c)
+	hermes.limit = big.NewInt(4)
+
+	size, err := maxChunkSize(context.Background(), cfg, c)
+	require.NoError(err)
+	require.Equal(4, size)
+
+	// an earlier run distributed to a single recipient, and a chunk of 4 takes more gas than the maximum
+	hermes.distributedCount[stringToBytes32("alpha")] = 1
+
+	// the dry run previews the chunks the distribution sends, without persisting the plan
+	report, err := Simulate(context.Background(), cfg, c)
+	require.NoError(err)
+	require.Equal(uint64(1), report.Delegates[0].DistributedCount)
+	var previewed []int
+	for _, chunk := range report.Delegates[0].Chunks {
+		previewed = append(previewed, len(chunk.Recipients))
+	}
+	require.Equal([]int{3, 3, 1}, previewed)
+	plan, err := dao.FindPlanByEndEpoch(24)
+	require.NoError(err)
+	require.Nil(plan)
+
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+	var chunks []int
+	for _, act := range c.Actions() {
+		if act.Method != "distributeRewards" {
+			continue
+		}
+		chunks = append(chunks, len(act.Args[2].([]common.Address)))
+		require.True(act.Gas.Limit <= cfg.Gas.MaxLimit)
+	}
+	require.Equal([]int{3, 3, 1}, chunks)
+	require.Equal(8, hermes.committedCount[stringToBytes32("alpha")][24])
+
+	// a single recipient above the maximum cannot be sent
+	cfg.Gas.MaxLimit = 30000
+	ch, err := newChunker(context.Background(), cfg, c)
+	require.NoError(err)
+	_, err = ch.next(context.Background(), "alpha", big.NewInt(48), big.NewInt(5),
+		[]common.Address{common.BytesToAddress(testAddress(1).Bytes())}, []*big.Int{big.NewInt(100)})
+	require.Equal(chain.ErrGasLimitExceeded, errors.Cause(err))
+}
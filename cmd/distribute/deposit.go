@@ -4,31 +4,59 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
-	"log"
 	"math/big"
 	"strings"
 	"sync"
-	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gogo/protobuf/proto"
 	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-address/address"
-	"github.com/iotexproject/iotex-antenna-go/v2/account"
-	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
 	"github.com/iotexproject/iotex-proto/golang/iotexapi"
 	"github.com/iotexproject/iotex-proto/golang/iotextypes"
 	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
+	"github.com/iotexproject/iotex-hermes/chain"
 	"github.com/iotexproject/iotex-hermes/cmd/dao"
-	"github.com/iotexproject/iotex-hermes/util"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/notify"
 )
 
+// SendCmd is the send command
+var SendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Send pending auto deposit records",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		NewSender(cfg, c).Send(context.Background())
+		return nil
+	},
+}
+
 // GetBucketID query bucketID from contract
-func GetBucketID(c iotex.AuthedClient, voter common.Address) (int64, error) {
-	cstring := util.MustFetchNonEmptyParam("AUTO_DEPOSIT_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+func GetBucketID(ctx context.Context, cfg *config.Config, c chain.Client, voter common.Address) (int64, error) {
+	caddr, err := address.FromString(cfg.Contracts.AutoDeposit)
 	if err != nil {
 		return 0, err
 	}
@@ -37,7 +65,7 @@ func GetBucketID(c iotex.AuthedClient, voter common.Address) (int64, error) {
 		return 0, err
 	}
 
-	data, err := c.Contract(caddr, autoDepositABI).Read("bucket", voter).Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, autoDepositABI, "bucket", voter)
 	if err != nil {
 		return 0, err
 	}
@@ -50,47 +78,51 @@ func GetBucketID(c iotex.AuthedClient, voter common.Address) (int64, error) {
 
 // Sender send drop record
 type Sender struct {
-	Accounts []account.Account
+	Clients []chain.Client
+
+	cfg *config.Config
 }
 
 type accountSender struct {
-	account   account.Account
+	cfg       *config.Config
+	client    chain.Client
 	records   []dao.DropRecord
 	waitGroup *sync.WaitGroup
 }
 
 var bucketStateMap = make(map[uint64]bool)
 
-func (s *accountSender) send() {
-	endpoint := util.MustFetchNonEmptyParam("IO_ENDPOINT")
-	conn, err := iotex.NewDefaultGRPCConn(endpoint)
-	if err != nil {
-		log.Fatalf("create grpc error: %v", err)
-	}
-	defer conn.Close()
-	client := iotex.NewAuthedClient(iotexapi.NewAPIServiceClient(conn), s.account)
-
+func (s *accountSender) send(ctx context.Context) {
 	for _, record := range s.records {
-		if record.Verify() != nil {
+		ctx := logger.WithFields(ctx,
+			logger.EndEpoch(record.EndEpoch),
+			logger.Delegate(record.DelegateName),
+			logger.RecordID(record.ID),
+			logger.BucketID(record.Index),
+			zap.String("voter", record.Voter))
+		if err := record.Verify(); err != nil {
+			logger.Ctx(ctx).Error("invalid drop record signature")
+			notify.Send(ctx, dropRecordEvent(&record, "error_signature", err))
 			record.Status = "error_signature"
-			err = record.Save(dao.DB())
+			err := record.Save(dao.DB())
 			if err != nil {
-				log.Fatalf("save drop records error: %v", err)
+				logger.Ctx(ctx).Fatal("failed to save drop record", zap.Error(err))
 			}
 			continue
 		}
 		amount, ok := big.NewInt(0).SetString(record.Amount, 10)
 		if !ok {
-			log.Printf("can't convert staking amount: %v\n", record.Amount)
+			logger.Ctx(ctx).Error("invalid drop record amount", zap.String("amount", record.Amount))
 		}
-		h, err := addDepositOrTransfer(client, record.ID, record.Index, record.Voter, amount)
+		h, err := addDepositOrTransfer(ctx, s.cfg, s.client, record.Index, record.Voter, amount)
 		if err != nil {
-			log.Printf("add deposit %d error: %v\n", record.ID, err)
+			logger.Ctx(ctx).Error("failed to add deposit", zap.Error(err))
+			notify.Send(ctx, dropRecordEvent(&record, "error", err))
 			record.Status = "error"
 			record.ErrorMessage = err.Error()
 			err = record.Save(dao.DB())
 			if err != nil {
-				log.Fatalf("save error drop records %d:%s error: %v", record.ID, record.Voter, err)
+				logger.Ctx(ctx).Fatal("failed to save drop record", zap.Error(err))
 			}
 			continue
 		}
@@ -99,7 +131,7 @@ func (s *accountSender) send() {
 		record.Status = "completed"
 		err = record.Save(dao.DB())
 		if err != nil {
-			log.Fatalf("save success drop records %d:%s error: %v", record.ID, record.Voter, err)
+			logger.Ctx(ctx).Fatal("failed to save drop record", zap.Error(err))
 		}
 	}
 
@@ -110,7 +142,18 @@ func (s *accountSender) send() {
 	}
 }
 
-func checkAutoStake(c iotex.AuthedClient, bucketID uint64) (bool, error) {
+func dropRecordEvent(record *dao.DropRecord, status string, err error) *notify.Event {
+	return &notify.Event{
+		Type:     notify.EventDropRecordFailed,
+		Message:  err.Error(),
+		EndEpoch: record.EndEpoch,
+		Delegate: record.DelegateName,
+		RecordID: record.ID,
+		Fields:   map[string]string{"status": status, "voter": record.Voter, "amount": record.Amount},
+	}
+}
+
+func checkAutoStake(ctx context.Context, c chain.Client, bucketID uint64) (bool, error) {
 	state, ok := bucketStateMap[bucketID]
 	if ok {
 		return state, nil
@@ -135,7 +178,7 @@ func checkAutoStake(c iotex.AuthedClient, bucketID uint64) (bool, error) {
 		return false, err
 	}
 
-	res, err := c.API().ReadState(context.Background(), &iotexapi.ReadStateRequest{
+	res, err := c.ReadState(ctx, &iotexapi.ReadStateRequest{
 		ProtocolID: []byte("staking"),
 		MethodName: methodBytes,
 		Arguments:  [][]byte{argumentsBytes},
@@ -157,83 +200,72 @@ func checkAutoStake(c iotex.AuthedClient, bucketID uint64) (bool, error) {
 }
 
 func addDepositOrTransfer(
-	c iotex.AuthedClient,
-	recordID uint,
+	ctx context.Context,
+	cfg *config.Config,
+	c chain.Client,
 	bucketID uint64,
 	voter string,
 	amount *big.Int,
 ) (hash.Hash256, error) {
-	ctx := context.Background()
-
-	gasPriceStr := util.MustFetchNonEmptyParam("GAS_PRICE")
-	gasPrice, ok := big.NewInt(0).SetString(gasPriceStr, 10)
-	if !ok {
-		return hash.ZeroHash256, errors.New("failed to convert string to big int")
+	autoStake, err := checkAutoStake(ctx, c, bucketID)
+	if err != nil {
+		logger.Ctx(ctx).Warn("failed to check auto stake of bucket", zap.Error(err))
 	}
-	gasLimit := 10000
 
-	gas := big.NewInt(0).Mul(gasPrice, big.NewInt(int64(gasLimit)))
-	if amount.Cmp(gas) <= 0 {
-		log.Printf("amount %s less than gas for %d\n", amount.String(), recordID)
-		return hash.ZeroHash256, nil
+	// the gas is paid out of the amount
+	estimator := chain.NewGasEstimator(cfg.Gas)
+	to, _ := address.FromString(voter)
+	var gas chain.Gas
+	if autoStake {
+		gas, err = estimator.Intrinsic(ctx, c)
+	} else {
+		gas, err = estimator.Transfer(ctx, c, to, amount)
 	}
-
-	autoStake, err := checkAutoStake(c, bucketID)
 	if err != nil {
-		log.Printf("check auto stake bucket error: %v", err)
+		return hash.ZeroHash256, err
+	}
+	if amount.Cmp(gas.Fee()) <= 0 {
+		logger.Ctx(ctx).Warn("skipped amount less than gas", zap.String("amount", amount.String()))
+		return hash.ZeroHash256, nil
 	}
 
 	var h hash.Hash256
 	if !autoStake {
-		to, _ := address.FromString(voter)
-		h, err = c.Transfer(to, big.NewInt(0).Sub(amount, gas)).SetGasPrice(gasPrice).SetGasLimit(uint64(gasLimit)).Call(ctx)
+		h, err = c.Transfer(ctx, to, new(big.Int).Sub(amount, gas.Fee()), gas)
 	} else {
-		h, err = c.Staking().AddDeposit(bucketID, big.NewInt(0).Sub(amount, gas)).SetGasPrice(gasPrice).SetGasLimit(uint64(gasLimit)).Call(ctx)
+		h, err = c.AddDeposit(ctx, bucketID, new(big.Int).Sub(amount, gas.Fee()), gas)
 	}
 
 	if err != nil {
 		return hash.ZeroHash256, err
 	}
-	time.Sleep(5 * time.Second)
-
-	for i := 0; i < 30; i++ {
-		resp, err := c.API().GetReceiptByAction(ctx, &iotexapi.GetReceiptByActionRequest{
-			ActionHash: hex.EncodeToString(h[:]),
-		})
-		if err != nil {
-			if strings.Contains(err.Error(), "code = NotFound") {
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			return hash.ZeroHash256, err
-		}
-		if resp.ReceiptInfo.Receipt.Status != 1 {
-			return hash.ZeroHash256, errors.Errorf("add deposit staking failed: %x", h)
-		}
-		return h, nil
+	if _, err := chain.NewReceiptWaiter(cfg.Receipt).Wait(ctx, c, h); err != nil {
+		return hash.ZeroHash256, errors.Wrapf(err, "add deposit staking failed, index=%d", bucketID)
 	}
-	return hash.ZeroHash256, errors.Errorf("add deposit error by exhausted retry, index=%d, hash: %x", bucketID, h)
+	logger.Ctx(ctx).Info("sent drop record", zap.Bool("autoStake", autoStake), logger.ActionHash(h))
+	return h, nil
 }
 
 // Send send records
-func (s *Sender) Send() {
-	fmt.Println("Begin add deposit to bucket")
+func (s *Sender) Send(ctx context.Context) {
+	logger.Ctx(ctx).Info("sending drop records")
 	for {
 		records, err := dao.FindNewDropRecordByLimit(10000)
 		if err != nil {
-			log.Fatalf("query drop records error: %v", err)
+			logger.Ctx(ctx).Fatal("failed to query drop records", zap.Error(err))
 		}
 		if len(records) == 0 {
 			break
 		}
 
-		shard := len(s.Accounts)
+		shard := len(s.Clients)
 		if len(records) < shard || shard == 1 {
 			sender := &accountSender{
-				account: s.Accounts[0],
+				cfg:     s.cfg,
+				client:  s.Clients[0],
 				records: records,
 			}
-			sender.send()
+			sender.send(ctx)
 		} else {
 			wg := sync.WaitGroup{}
 			wg.Add(shard)
@@ -244,31 +276,23 @@ func (s *Sender) Send() {
 					end = len(records)
 				}
 				sender := &accountSender{
-					account:   s.Accounts[i],
+					cfg:       s.cfg,
+					client:    s.Clients[i],
 					records:   records[i*size : end],
 					waitGroup: &wg,
 				}
-				go sender.send()
+				go sender.send(ctx)
 			}
 			wg.Wait()
 		}
 	}
-	fmt.Println("Add deposit to bucket successful.")
+	logger.Ctx(ctx).Info("sent drop records")
 }
 
-// NewSender new sender instance
-func NewSender() (*Sender, error) {
-	pwd := util.MustFetchNonEmptyParam("VAULT_PASSWORD")
-	acc, err := util.GetVaultAccount(pwd)
-	if err != nil {
-		return nil, err
-	}
-	// verify the account matches the reward address
-	if acc.Address().String() != util.MustFetchNonEmptyParam("VAULT_ADDRESS") {
-		return nil, fmt.Errorf("key and address do not match")
-	}
-
+// NewSender new sender instance sending with clients
+func NewSender(cfg *config.Config, clients ...chain.Client) *Sender {
 	return &Sender{
-		Accounts: []account.Account{acc},
-	}, nil
+		Clients: clients,
+		cfg:     cfg,
+	}
 }
@@ -8,179 +8,266 @@ package distribute
 
 import (
 	"context"
-	"encoding/hex"
 	"fmt"
 	"math/big"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/iotexproject/go-pkgs/hash"
 	"github.com/iotexproject/iotex-address/address"
-	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
-	"github.com/iotexproject/iotex-proto/golang/iotexapi"
 	"github.com/pkg/errors"
-	"github.com/shurcooL/graphql"
 	"github.com/spf13/cobra"
+	"go.uber.org/zap"
 
+	"github.com/iotexproject/iotex-hermes/chain"
 	"github.com/iotexproject/iotex-hermes/cmd/dao"
-	"github.com/iotexproject/iotex-hermes/util"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/metrics"
+	"github.com/iotexproject/iotex-hermes/notify"
 )
 
 // DistributeCmd is the distribute command
 var DistributeCmd = &cobra.Command{
-	Use:   "distribute DELEGATE",
-	Short: "Distribute rewards for delegate",
-	Args:  cobra.MinimumNArgs(1),
+	Use:   "distribute",
+	Short: "Distribute the rewards of the next window",
+	Args:  cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
-		return Reward()
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+		ctx := context.Background()
+		if dryRun {
+			report, err := Simulate(ctx, cfg, c)
+			if err != nil {
+				return err
+			}
+			switch output {
+			case "json":
+				return report.WriteJSON(cmd.OutOrStdout())
+			case "table":
+				return report.WriteTable(cmd.OutOrStdout())
+			default:
+				return fmt.Errorf("unknown output format %s", output)
+			}
+		}
+		return Reward(ctx, cfg, c, nil)
 	},
 }
 
+var (
+	dryRun bool
+	output string
+)
+
+func init() {
+	DistributeCmd.Flags().BoolVar(&dryRun, "dry-run", false, "preview the distribution without sending any action")
+	DistributeCmd.Flags().StringVarP(&output, "output", "o", "table", "dry run report format, table or json")
+}
+
 // DistributionInfo defines the distribution information
 type DistributionInfo struct {
 	DelegateName  string
+	StartEpoch    uint64
 	RecipientList []common.Address
 	AmountList    []*big.Int
+	ServiceFee    *big.Int
+	FeePolicy     string
+	Refund        *big.Int
 }
 
-// Reward distribute reward to voter group by delegate
-func Reward() error {
-	pwd := util.MustFetchNonEmptyParam("VAULT_PASSWORD")
-	account, err := util.GetVaultAccount(pwd)
+// Reward distribute reward to voter group by delegate, recording the progress in cycle if it is not nil
+func Reward(ctx context.Context, cfg *config.Config, c chain.Client, cycle *dao.Cycle) error {
+	// query GraphQL to get the distribution list
+	window, err := getDistribution(ctx, cfg, c)
 	if err != nil {
 		return err
 	}
-	// verify the account matches the reward address
-	if account.Address().String() != util.MustFetchNonEmptyParam("VAULT_ADDRESS") {
-		return fmt.Errorf("key and address do not match")
+	endEpoch, tip, distributions := window.endEpoch, window.minTips, window.distributions
+	ctx = logger.WithFields(ctx, logger.EndEpoch(endEpoch.Uint64()))
+	if cycle != nil {
+		if cycle.EndEpoch != endEpoch.Uint64() {
+			return fmt.Errorf("cycle end epoch %d does not match distribution end epoch %d", cycle.EndEpoch, endEpoch.Uint64())
+		}
+		if cycle.Status == dao.CycleClaimed {
+			if err := cycle.Advance(dao.CycleBookkeepingFetched); err != nil {
+				return err
+			}
+		}
 	}
 
-	endpoint := util.MustFetchNonEmptyParam("IO_ENDPOINT")
-	conn, err := iotex.NewDefaultGRPCConn(endpoint)
+	// follow the plan persisted on the first fetch, in case the bookkeeping shifted since
+	plan, err := loadPlan(ctx, cfg, c, window, false)
 	if err != nil {
 		return err
 	}
-	defer conn.Close()
-	c := iotex.NewAuthedClient(iotexapi.NewAPIServiceClient(conn), account)
+	if window.distributions, err = plan.distributions(); err != nil {
+		return err
+	}
+	if err := saveServiceFees(plan); err != nil {
+		return err
+	}
+	distributions = window.distributions
 
-	// query GraphQL to get the distribution list
-	endEpoch, tip, distributions, err := getDistribution(c)
-	if err != nil {
+	distributedDelegates := 0
+	if cycle != nil {
+		distributedDelegates = cycle.DistributedDelegates
+	}
+	if err := preflight(ctx, cfg, c, window, plan, distributedDelegates); err != nil {
 		return err
 	}
 
 	// call distribution contract to send out rewards
-	chunkSizeStr := util.MustFetchNonEmptyParam("CHUNK_SIZE")
-	chunkSize, err := strconv.Atoi(chunkSizeStr)
+	ch, err := newChunker(ctx, cfg, c)
 	if err != nil {
 		return err
 	}
 	delegateNames := make([][32]byte, 0, len(distributions))
-	for _, dist := range distributions {
+	for i, dist := range distributions {
 		delegateNames = append(delegateNames, stringToBytes32(dist.DelegateName))
-		divAddrList, divAmountList, err := splitRecipients(chunkSize, dist.RecipientList, dist.AmountList)
-		if err != nil {
-			return err
+		if cycle != nil && i < cycle.DistributedDelegates {
+			continue
 		}
+		ctx := logger.WithFields(ctx, logger.Delegate(dist.DelegateName))
 		for {
-			distrbutedCount, err := getDistributedCount(c, dist.DelegateName)
+			distributedCount, err := getDistributedCount(ctx, cfg, c, dist.DelegateName)
 			if err != nil {
 				return err
 			}
 			// distribution is done for the delegate
-			if int(distrbutedCount) == len(dist.RecipientList) {
+			if int(distributedCount) == len(dist.RecipientList) {
 				break
 			}
-			if int(distrbutedCount)%chunkSize != 0 {
+			if int(distributedCount) > len(dist.RecipientList) {
 				return fmt.Errorf("invalid distributed count, Delegate Name: %s, Distributed Count: %d, Number of Recipients: %d",
-					dist.DelegateName, distrbutedCount, len(dist.RecipientList))
+					dist.DelegateName, distributedCount, len(dist.RecipientList))
+			}
+			// the planned chunk is cut from the distributed count, and shrunk further if its gas exceeds the limit
+			end := plan.Delegates[i].nextChunkEnd(int(distributedCount))
+			recipients, amounts := dist.RecipientList[distributedCount:end], dist.AmountList[distributedCount:end]
+			size, err := ch.next(ctx, dist.DelegateName, endEpoch, tip, recipients, amounts)
+			if err != nil {
+				return err
+			}
+			if err := sendRewards(ctx, cfg, c, dist.DelegateName, endEpoch, tip, recipients[:size], amounts[:size]); err != nil {
+				return err
 			}
-			nextGroup := int(distrbutedCount) / chunkSize
-			if err := sendRewards(c, dist.DelegateName, endEpoch, tip, divAddrList[nextGroup], divAmountList[nextGroup]); err != nil {
+		}
+		if cycle != nil {
+			cycle.DistributedDelegates = i + 1
+			if err := cycle.Advance(dao.CycleDistributing); err != nil {
 				return err
 			}
 		}
 	}
-	return commitDistributions(c, endEpoch, delegateNames)
+	if err := commitDistributions(ctx, cfg, c, endEpoch, delegateNames); err != nil {
+		return err
+	}
+	if cycle != nil {
+		if err := cycle.Advance(dao.CycleCommitted); err != nil {
+			return err
+		}
+	}
+	if err := SweepFees(ctx, cfg, c, endEpoch.Uint64()); err != nil {
+		return err
+	}
+	// the report is saved again once the auto deposits are sent, or by the report command
+	if _, err := SaveDistributionReport(ctx, cfg, c, endEpoch.Uint64()); err != nil {
+		logger.Ctx(ctx).Error("failed to save distribution report", zap.Error(err))
+	}
+	return nil
 }
 
-func getDistribution(c iotex.AuthedClient) (*big.Int, *big.Int, []*DistributionInfo, error) {
-	minTips, err := getMinTips(c)
-	if err != nil {
-		return nil, nil, nil, err
-	}
+// distributionWindow defines the epoch range of a distribution and its bookkeeping
+type distributionWindow struct {
+	startEpoch    uint64
+	endEpoch      *big.Int
+	minTips       *big.Int
+	distributions []*DistributionInfo
+}
 
-	lastEndEpoch, err := GetLastEndEpoch(c)
+func getDistribution(ctx context.Context, cfg *config.Config, c chain.Client) (*distributionWindow, error) {
+	minTips, err := getMinTips(ctx, cfg, c)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	startEpoch := lastEndEpoch + 1
 
-	resp, err := c.API().GetChainMeta(context.Background(), &iotexapi.GetChainMetaRequest{})
+	window, err := NextWindow(ctx, cfg, c)
 	if err != nil {
-		return nil, nil, nil, err
-	}
-	curEpoch := resp.ChainMeta.Epoch.Num
-
-	endEpoch := startEpoch + 23
-
-	if endEpoch+2 > curEpoch {
-		return nil, nil, nil, fmt.Errorf("invalid end epoch, Current Epoch: %d, End Epoch: %d",
-			curEpoch, endEpoch)
+		return nil, err
 	}
-
-	if startEpoch > endEpoch {
-		return nil, nil, nil, fmt.Errorf("invalid epoch range, Start Epoch: %d, End Epoch: %d",
-			startEpoch, endEpoch)
+	if !window.Due(cfg) {
+		return nil, fmt.Errorf("invalid end epoch, Current Epoch: %d, End Epoch: %d, Finality Lag: %d",
+			window.CurrentEpoch, window.EndEpoch, cfg.Schedule.FinalityLag)
 	}
 
-	fmt.Printf("Distribution Start Epoch: %d\n", startEpoch)
-	fmt.Printf("Distribution End Epoch: %d\n", endEpoch)
+	logger.Ctx(ctx).Info("distribution window",
+		zap.Uint64("startEpoch", window.StartEpoch),
+		logger.EndEpoch(window.EndEpoch),
+		zap.String("minTips", minTips.String()))
 
-	rewardAddress := c.Account().Address().String()
-	epochCount := endEpoch - startEpoch + 1
-	distributions, err := getBookkeeping(startEpoch, epochCount, rewardAddress)
+	distributions, err := getBookkeeping(ctx, cfg, c, window)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, err
 	}
-	return big.NewInt(int64(endEpoch)), minTips, distributions, nil
+	return &distributionWindow{
+		startEpoch:    window.StartEpoch,
+		endEpoch:      new(big.Int).SetUint64(window.EndEpoch),
+		minTips:       minTips,
+		distributions: distributions,
+	}, nil
 }
 
 func sendRewards(
-	c iotex.AuthedClient,
+	ctx context.Context,
+	cfg *config.Config,
+	c chain.Client,
 	delegateName string,
 	endEpoch *big.Int,
 	minTips *big.Int,
 	voterAddrList []common.Address,
 	amountList []*big.Int,
 ) error {
-	cstring := util.MustFetchNonEmptyParam("HERMES_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
 	if err != nil {
 		return err
 	}
 
 	// call distribution contract to send out rewards
-	ctx := context.Background()
 	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
 	if err != nil {
 		return err
 	}
 
+	groupAmount := big.NewInt(0)
+	for _, amount := range amountList {
+		groupAmount.Add(groupAmount, amount)
+	}
+
+	bucketIDs := getBucketIDs(ctx, cfg, c, voterAddrList)
 	for i := 0; i < len(voterAddrList); i++ {
-		bucketID, err := GetBucketID(c, voterAddrList[i])
-		if err != nil {
-			fmt.Printf("Query bucketID from contract error: %v\n", err)
-			continue
-		}
+		bucketID := bucketIDs[i]
 		if bucketID != -1 {
 			addr, err := address.FromBytes(voterAddrList[i][:])
 			if err != nil {
-				fmt.Printf("Convert address error: %v\n", err)
+				logger.Ctx(ctx).Warn("failed to convert voter address", zap.Error(err))
 				continue
 			}
 			drop := dao.DropRecord{
@@ -193,7 +280,8 @@ func sendRewards(
 			}
 			err = drop.Save(dao.DB())
 			if err != nil {
-				fmt.Printf("Save drop record error: %v\n", err)
+				logger.Ctx(ctx).Warn("failed to save drop record", zap.String("voter", drop.Voter),
+					logger.BucketID(drop.Index), zap.Error(err))
 				continue
 			}
 			amountList[i] = big.NewInt(0)
@@ -204,98 +292,90 @@ func sendRewards(
 	for _, amount := range amountList {
 		totalAmount.Add(totalAmount, amount)
 	}
-	fmt.Printf("Delegate Name: %s, Group Total Voter Count: %d, Group Total Amount: %s, Tip: %s\n", delegateName,
-		len(voterAddrList), totalAmount.String(), minTips.String())
+	logger.Ctx(ctx).Info("distributing rewards",
+		zap.Int("voters", len(voterAddrList)),
+		zap.String("totalAmount", totalAmount.String()),
+		zap.String("tips", minTips.String()))
 
 	name := stringToBytes32(delegateName)
 
-	gasPriceStr := util.MustFetchNonEmptyParam("GAS_PRICE")
-	gasPrice, ok := big.NewInt(0).SetString(gasPriceStr, 10)
-	if !ok {
-		return errors.New("failed to convert string to big int")
-	}
-	gasLimitStr := util.MustFetchNonEmptyParam("GAS_LIMIT")
-	gasLimit, err := strconv.Atoi(gasLimitStr)
+	gas, err := chain.NewGasEstimator(cfg.Gas).Execution(ctx, c, caddr, hermesABI, totalAmount, "distributeRewards",
+		name, endEpoch, voterAddrList, amountList)
 	if err != nil {
 		return err
 	}
-	h, err := c.Contract(caddr, hermesABI).Execute("distributeRewards", name, endEpoch, voterAddrList, amountList).
-		SetAmount(totalAmount).SetGasPrice(gasPrice).SetGasLimit(uint64(gasLimit)).Call(ctx)
+	h, err := c.ExecuteContract(ctx, caddr, hermesABI, totalAmount, gas, "distributeRewards",
+		name, endEpoch, voterAddrList, amountList)
 	if err != nil {
 		return err
 	}
-	sleepIntervalStr := util.MustFetchNonEmptyParam("SLEEP_INTERVAL")
-	sleepInterval, err := strconv.Atoi(sleepIntervalStr)
-	if err != nil {
+	if _, err := chain.NewReceiptWaiter(cfg.Receipt).Wait(ctx, c, h); err != nil {
+		err = errors.Wrap(err, "distributeRewards failed")
+		notify.Send(ctx, &notify.Event{
+			Type:     notify.EventDistributeFailed,
+			Message:  err.Error(),
+			EndEpoch: endEpoch.Uint64(),
+			Delegate: delegateName,
+			Fields:   map[string]string{"voters": strconv.Itoa(len(voterAddrList)), "amount": totalAmount.String()},
+		})
 		return err
 	}
-	time.Sleep(time.Duration(sleepInterval) * time.Second)
+	logger.Ctx(ctx).Info("distributed rewards", logger.ActionHash(h))
+	metrics.DistributedAmount.WithLabelValues(delegateName).Add(metrics.IOTX(groupAmount))
+	return nil
+}
 
-	resp, err := c.API().GetReceiptByAction(ctx, &iotexapi.GetReceiptByActionRequest{
-		ActionHash: hex.EncodeToString(h[:]),
-	})
-	if err != nil {
-		return err
-	}
-	if resp.ReceiptInfo.Receipt.Status != 1 {
-		return errors.Errorf("distributeRewards failed: %x", h)
+// getBucketIDs returns the auto deposit bucket of every voter, -1 if the voter does not register one
+func getBucketIDs(ctx context.Context, cfg *config.Config, c chain.Client, voterAddrList []common.Address) []int64 {
+	bucketIDs := make([]int64, len(voterAddrList))
+	for i, voter := range voterAddrList {
+		bucketID, err := GetBucketID(ctx, cfg, c, voter)
+		if err != nil {
+			logger.Ctx(ctx).Warn("failed to query auto deposit bucket", zap.String("voter", voter.Hex()), zap.Error(err))
+			bucketID = -1
+		}
+		bucketIDs[i] = bucketID
 	}
-	return nil
+	return bucketIDs
 }
 
-func commitDistributions(c iotex.AuthedClient, endEpoch *big.Int, delegateNames [][32]byte) error {
-	cstring := util.MustFetchNonEmptyParam("HERMES_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+func commitDistributions(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch *big.Int, delegateNames [][32]byte) error {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
 	if err != nil {
 		return err
 	}
 
 	// call distribution contract to send out rewards
-	ctx := context.Background()
 	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
 	if err != nil {
 		return err
 	}
 
-	gasPriceStr := util.MustFetchNonEmptyParam("GAS_PRICE")
-	gasPrice, ok := big.NewInt(0).SetString(gasPriceStr, 10)
-	if !ok {
-		return errors.New("failed to convert string to big int")
+	gas, err := chain.NewGasEstimator(cfg.Gas).Execution(ctx, c, caddr, hermesABI, nil, "commitDistributions",
+		endEpoch, delegateNames)
+	var h hash.Hash256
+	if err == nil {
+		h, err = c.ExecuteContract(ctx, caddr, hermesABI, nil, gas, "commitDistributions", endEpoch, delegateNames)
 	}
-	gasLimitStr := util.MustFetchNonEmptyParam("GAS_LIMIT")
-	gasLimit, err := strconv.Atoi(gasLimitStr)
-	if err != nil {
-		return err
+	if err == nil {
+		_, err = chain.NewReceiptWaiter(cfg.Receipt).Wait(ctx, c, h)
 	}
-	h, err := c.Contract(caddr, hermesABI).Execute("commitDistributions", endEpoch, delegateNames).
-		SetGasPrice(gasPrice).SetGasLimit(uint64(gasLimit)).Call(ctx)
-	if err != nil {
-		return err
-	}
-	sleepIntervalStr := util.MustFetchNonEmptyParam("SLEEP_INTERVAL")
-	sleepInterval, err := strconv.Atoi(sleepIntervalStr)
-	if err != nil {
-		return err
-	}
-	time.Sleep(time.Duration(sleepInterval) * time.Second)
-
-	resp, err := c.API().GetReceiptByAction(ctx, &iotexapi.GetReceiptByActionRequest{
-		ActionHash: hex.EncodeToString(h[:]),
-	})
 	if err != nil {
+		err = errors.Wrap(err, "commitDistributions failed")
+		notify.Send(ctx, &notify.Event{
+			Type:     notify.EventCommitFailed,
+			Message:  err.Error(),
+			EndEpoch: endEpoch.Uint64(),
+			Fields:   map[string]string{"delegates": strconv.Itoa(len(delegateNames))},
+		})
 		return err
 	}
-	if resp.ReceiptInfo.Receipt.Status != 1 {
-		return errors.Errorf("commitDistributions failed: %x", h)
-	}
-
-	fmt.Println("successfully distribute rewards")
+	logger.Ctx(ctx).Info("committed distributions", zap.Int("delegates", len(delegateNames)), logger.ActionHash(h))
 	return nil
 }
 
-func getMinTips(c iotex.AuthedClient) (*big.Int, error) {
-	cstring := util.MustFetchNonEmptyParam("MULTISEND_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+func getMinTips(ctx context.Context, cfg *config.Config, c chain.Client) (*big.Int, error) {
+	caddr, err := address.FromString(cfg.Contracts.Multisend)
 	if err != nil {
 		return nil, err
 	}
@@ -303,7 +383,7 @@ func getMinTips(c iotex.AuthedClient) (*big.Int, error) {
 	if err != nil {
 		return nil, err
 	}
-	data, err := c.Contract(caddr, multisendABI).Read("minTips").Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, multisendABI, "minTips")
 	if err != nil {
 		return nil, err
 	}
@@ -311,14 +391,11 @@ func getMinTips(c iotex.AuthedClient) (*big.Int, error) {
 	if err := data.Unmarshal(&minTips); err != nil {
 		return nil, err
 	}
-
-	fmt.Printf("MultiSend Contract: %s, min tip: %s\n", cstring, minTips.String())
 	return minTips, nil
 }
 
-func getContractStartEpoch(c iotex.AuthedClient) (uint64, error) {
-	cstring := util.MustFetchNonEmptyParam("HERMES_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+func getContractStartEpoch(ctx context.Context, cfg *config.Config, c chain.Client) (uint64, error) {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
 	if err != nil {
 		return 0, err
 	}
@@ -326,7 +403,7 @@ func getContractStartEpoch(c iotex.AuthedClient) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	data, err := c.Contract(caddr, hermesABI).Read("contractStartEpoch").Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, hermesABI, "contractStartEpoch")
 	if err != nil {
 		return 0, err
 	}
@@ -338,9 +415,20 @@ func getContractStartEpoch(c iotex.AuthedClient) (uint64, error) {
 }
 
 // GetLastEndEpoch get last end epoch from hermes contract
-func GetLastEndEpoch(c iotex.AuthedClient) (uint64, error) {
-	cstring := util.MustFetchNonEmptyParam("HERMES_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+func GetLastEndEpoch(ctx context.Context, cfg *config.Config, c chain.Client) (uint64, error) {
+	endEpochCount, err := getEndEpochCount(ctx, cfg, c)
+	if err != nil {
+		return 0, err
+	}
+	if endEpochCount == 0 {
+		return 0, nil
+	}
+	return getEndEpoch(ctx, cfg, c, endEpochCount-1)
+}
+
+// getEndEpochCount returns the number of committed windows
+func getEndEpochCount(ctx context.Context, cfg *config.Config, c chain.Client) (uint64, error) {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
 	if err != nil {
 		return 0, err
 	}
@@ -348,7 +436,7 @@ func GetLastEndEpoch(c iotex.AuthedClient) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	data, err := c.Contract(caddr, hermesABI).Read("getEndEpochCount").Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, hermesABI, "getEndEpochCount")
 	if err != nil {
 		return 0, err
 	}
@@ -356,24 +444,58 @@ func GetLastEndEpoch(c iotex.AuthedClient) (uint64, error) {
 	if err := data.Unmarshal(&endEpochCount); err != nil {
 		return 0, err
 	}
+	return endEpochCount.Uint64(), nil
+}
 
-	if endEpochCount.String() == "0" {
-		return 0, nil
+// getEndEpoch returns the end epoch of the index-th committed window
+func getEndEpoch(ctx context.Context, cfg *config.Config, c chain.Client, index uint64) (uint64, error) {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
+	if err != nil {
+		return 0, err
+	}
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		return 0, err
 	}
-	data, err = c.Contract(caddr, hermesABI).Read("endEpochs", endEpochCount.Sub(endEpochCount, big.NewInt(1))).Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, hermesABI, "endEpochs", new(big.Int).SetUint64(index))
 	if err != nil {
 		return 0, err
 	}
-	var lastEndEpoch *big.Int
-	if err := data.Unmarshal(&lastEndEpoch); err != nil {
+	var endEpoch *big.Int
+	if err := data.Unmarshal(&endEpoch); err != nil {
 		return 0, err
 	}
-	return lastEndEpoch.Uint64(), nil
+	return endEpoch.Uint64(), nil
 }
 
-func getDistributedCount(c iotex.AuthedClient, delegateName string) (uint64, error) {
-	cstring := util.MustFetchNonEmptyParam("HERMES_CONTRACT_ADDRESS")
-	caddr, err := address.FromString(cstring)
+// getCommittedDistribution returns the number of recipients of the delegate committed in the window ending at
+// endEpoch, and the amount distributed to them
+func getCommittedDistribution(ctx context.Context, cfg *config.Config, c chain.Client, delegateName string, endEpoch uint64) (uint64, *big.Int, error) {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
+	if err != nil {
+		return 0, nil, err
+	}
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		return 0, nil, err
+	}
+	data, err := c.ReadContract(ctx, caddr, hermesABI, "distributions", stringToBytes32(delegateName),
+		new(big.Int).SetUint64(endEpoch))
+	if err != nil {
+		return 0, nil, err
+	}
+	var distribution struct {
+		DistributedCount *big.Int
+		Amount           *big.Int
+	}
+	if err := data.Unmarshal(&distribution); err != nil {
+		return 0, nil, err
+	}
+	return distribution.DistributedCount.Uint64(), distribution.Amount, nil
+}
+
+func getDistributedCount(ctx context.Context, cfg *config.Config, c chain.Client, delegateName string) (uint64, error) {
+	caddr, err := address.FromString(cfg.Contracts.Hermes)
 	if err != nil {
 		return 0, err
 	}
@@ -383,7 +505,7 @@ func getDistributedCount(c iotex.AuthedClient, delegateName string) (uint64, err
 	}
 
 	name := stringToBytes32(delegateName)
-	data, err := c.Contract(caddr, hermesABI).Read("distributedCount", name).Call(context.Background())
+	data, err := c.ReadContract(ctx, caddr, hermesABI, "distributedCount", name)
 	if err != nil {
 		return 0, err
 	}
@@ -394,97 +516,52 @@ func getDistributedCount(c iotex.AuthedClient, delegateName string) (uint64, err
 	return distributedCount.Uint64(), nil
 }
 
-func getBookkeeping(startEpoch uint64, epochCount uint64, rewardAddress string) ([]*DistributionInfo, error) {
-	type query struct {
-		Hermes struct {
-			Exist              graphql.Boolean
-			HermesDistribution []struct {
-				DelegateName       graphql.String
-				RewardDistribution []struct {
-					VoterIotexAddress graphql.String
-					Amount            graphql.String
-				}
-				StakingIotexAddress graphql.String
-				VoterCount          graphql.Int
-				WaiveServiceFee     graphql.Boolean
-				Refund              graphql.String
+// distributionsOf charges the service fees and sorts the recipients of every delegate of bookkeeping, paying the
+// replacement in overrides of an overridden voter or staking address
+func distributionsOf(cfg *config.Config, bookkeeping *Bookkeeping, overrides map[string]string) ([]*DistributionInfo, error) {
+	distributions := make([]*DistributionInfo, 0, len(bookkeeping.Delegates))
+	for _, delegate := range bookkeeping.Delegates {
+		distributionMap := make(map[string]*big.Int)
+		pay := func(recipient string, amount *big.Int) {
+			if replacement, ok := overrides[recipient]; ok {
+				logger.L().Debug("overrode recipient address", logger.Delegate(delegate.DelegateName),
+					zap.String("address", recipient), zap.String("replacement", replacement))
+				recipient = replacement
 			}
-		} `graphql:"hermes(startEpoch: $startEpoch, epochCount: $epochCount, rewardAddress: $rewardAddress, waiverThreshold: $waiverThreshold)"`
-	}
-
-	analyticsEndpoint := util.MustFetchNonEmptyParam("ANALYTICS_ENDPOINT")
-
-	gqlClient := graphql.NewClient(analyticsEndpoint, nil)
-
-	waiverThresholdStr := util.MustFetchNonEmptyParam("WAIVER_THRESHOLD")
-	waiverThreshold, err := strconv.Atoi(waiverThresholdStr)
-	if err != nil {
-		return nil, err
-	}
-
-	// make sure every epoch does not miss hermes info
-	for epoch := startEpoch; epoch < startEpoch+epochCount; epoch++ {
-		tempVariables := map[string]interface{}{
-			"startEpoch":      graphql.Int(epoch),
-			"epochCount":      graphql.Int(1),
-			"rewardAddress":   graphql.String(rewardAddress),
-			"waiverThreshold": graphql.Int(waiverThreshold),
-		}
-		var tempOutput query
-		if err := gqlClient.Query(context.Background(), &tempOutput, tempVariables); err != nil {
-			return nil, err
-		}
-		if !tempOutput.Hermes.Exist {
-			return nil, errors.New(fmt.Sprintf("bookkeeping info doesn't exist for Epoch %d\n", epoch))
+			if _, ok := distributionMap[recipient]; !ok {
+				distributionMap[recipient] = new(big.Int)
+			}
+			distributionMap[recipient].Add(distributionMap[recipient], amount)
 		}
-	}
-
-	variables := map[string]interface{}{
-		"startEpoch":      graphql.Int(startEpoch),
-		"epochCount":      graphql.Int(epochCount),
-		"rewardAddress":   graphql.String(rewardAddress),
-		"waiverThreshold": graphql.Int(waiverThreshold),
-	}
-	var output query
-	if err := gqlClient.Query(context.Background(), &output, variables); err != nil {
-		return nil, err
-	}
-
-	if !output.Hermes.Exist {
-		return nil, errors.New("bookkeeping info doesn't exist within the epoch range")
-	}
-
-	distributions := make([]*DistributionInfo, 0, len(output.Hermes.HermesDistribution))
-	for _, hermesDistribution := range output.Hermes.HermesDistribution {
-		distributionMap := make(map[string]*big.Int)
-		for _, rewardDistribution := range hermesDistribution.RewardDistribution {
-			amount, ok := big.NewInt(0).SetString(string(rewardDistribution.Amount), 10)
+		reward := big.NewInt(0)
+		for _, r := range delegate.Rewards {
+			amount, ok := big.NewInt(0).SetString(r.Amount, 10)
 			if !ok {
 				return nil, errors.New("failed to convert string to big int")
 			}
-			distributionMap[string(rewardDistribution.VoterIotexAddress)] = amount
+			pay(r.Voter, amount)
+			reward.Add(reward, amount)
 		}
 		// Add delegate to the map
-		refund, ok := big.NewInt(0).SetString(string(hermesDistribution.Refund), 10)
+		refund, ok := big.NewInt(0).SetString(delegate.Refund, 10)
 		if !ok {
 			return nil, errors.New("failed to convert string to big int")
 		}
+		reward.Add(reward, refund)
 		// charge fees
-		serviceFee := big.NewInt(0)
-		if !hermesDistribution.WaiveServiceFee {
-			if serviceFee, refund, err = calculateServiceFee(int64(hermesDistribution.VoterCount), refund); err != nil {
+		serviceFee, feePolicy := big.NewInt(0), FeePolicyWaived
+		if !delegate.WaiveServiceFee {
+			name, policy, err := feePolicyOf(cfg, delegate.DelegateName)
+			if err != nil {
 				return nil, err
 			}
+			feePolicy = name
+			serviceFee, refund = chargeServiceFee(policy, delegate.VoterCount, reward, refund)
 		}
-		fmt.Printf("Delegate Name: %s, Service Fee: %s, Refund: %s\n", string(hermesDistribution.DelegateName),
-			serviceFee.String(), refund.String())
-
-		delegateIotexStakingAddr := string(hermesDistribution.StakingIotexAddress)
-		if _, ok := distributionMap[delegateIotexStakingAddr]; !ok {
-			distributionMap[delegateIotexStakingAddr] = refund
-		} else {
-			distributionMap[delegateIotexStakingAddr].Add(distributionMap[delegateIotexStakingAddr], refund)
-		}
+		logger.L().Debug("charged service fee", logger.Delegate(delegate.DelegateName), zap.String("feePolicy", feePolicy),
+			zap.String("serviceFee", serviceFee.String()), zap.String("refund", refund.String()))
+
+		pay(delegate.StakingAddress, refund)
 
 		var keys []string
 		for k := range distributionMap {
@@ -505,9 +582,13 @@ func getBookkeeping(startEpoch uint64, epochCount uint64, rewardAddress string)
 		}
 
 		distributions = append(distributions, &DistributionInfo{
-			DelegateName:  string(hermesDistribution.DelegateName),
+			DelegateName:  delegate.DelegateName,
+			StartEpoch:    bookkeeping.StartEpoch,
 			RecipientList: recipientAddrList,
 			AmountList:    amountList,
+			ServiceFee:    serviceFee,
+			FeePolicy:     feePolicy,
+			Refund:        new(big.Int).Set(refund),
 		})
 	}
 	// sort distributions by delegate name
@@ -516,57 +597,8 @@ func getBookkeeping(startEpoch uint64, epochCount uint64, rewardAddress string)
 	return distributions, nil
 }
 
-func calculateServiceFee(voterCount int64, refund *big.Int) (*big.Int, *big.Int, error) {
-	baseChargeStr := util.MustFetchNonEmptyParam("BASE_CHARGE")
-	baseCharge, ok := big.NewInt(0).SetString(baseChargeStr, 10)
-	if !ok {
-		return nil, nil, errors.New("failed to convert string to big int")
-	}
-	chargePerRecipientStr := util.MustFetchNonEmptyParam("CHARGE_PER_RECIPIENT")
-	chargePerRecipient, ok := big.NewInt(0).SetString(chargePerRecipientStr, 10)
-	if !ok {
-		return nil, nil, errors.New("failed to convert string to big int")
-	}
-	serviceFee := baseCharge
-	extraCharge := big.NewInt(voterCount)
-	extraCharge.Mul(extraCharge, chargePerRecipient)
-	serviceFee.Add(serviceFee, extraCharge)
-	balance := new(big.Int).Set(refund)
-	refund.Sub(refund, serviceFee)
-	if refund.Sign() < 0 {
-		refund = big.NewInt(0)
-		serviceFee = balance
-	}
-	return serviceFee, refund, nil
-}
-
-func splitRecipients(chunkSize int, recipientAddrList []common.Address, amountList []*big.Int) ([][]common.Address, [][]*big.Int, error) {
-	if len(recipientAddrList) != len(amountList) {
-		return nil, nil, errors.New("length does not match")
-	}
-	var divAddrList [][]common.Address
-	var divAmountList [][]*big.Int
-
-	for i := 0; i < len(recipientAddrList); i += chunkSize {
-		end := i + chunkSize
-
-		if end > len(recipientAddrList) {
-			end = len(recipientAddrList)
-		}
-
-		divAddrList = append(divAddrList, recipientAddrList[i:end])
-		divAmountList = append(divAmountList, amountList[i:end])
-	}
-
-	return divAddrList, divAmountList, nil
-}
-
 // ioAddrToEvmAddr converts IoTeX address into evm address
 func ioAddrToEvmAddr(ioAddr string) (common.Address, error) {
-	// temporary fix
-	if ioAddr == "io16y9wk2xnwurvtgmd2mds2gcdfe2lmzad6dcw29" {
-		ioAddr = "io16dkdajys8609qxf78wmmzssgfgvqkk0funzp0r"
-	}
 	address, err := address.FromString(ioAddr)
 	if err != nil {
 		return common.Address{}, err
@@ -574,6 +606,15 @@ func ioAddrToEvmAddr(ioAddr string) (common.Address, error) {
 	return common.BytesToAddress(address.Bytes()), nil
 }
 
+// evmAddrToIoAddr converts evm address into IoTeX address
+func evmAddrToIoAddr(addr common.Address) (string, error) {
+	ioAddr, err := address.FromBytes(addr.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return ioAddr.String(), nil
+}
+
 // stringToBytes32 converts string to bytes32
 func stringToBytes32(delegateName string) [32]byte {
 	var name [32]byte
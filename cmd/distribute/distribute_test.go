@@ -7,13 +7,36 @@
 package distribute
 
 import (
-	"os"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gogo/protobuf/proto"
+	"github.com/iotexproject/iotex-address/address"
 	"github.com/iotexproject/iotex-antenna-go/v2/account"
 	"github.com/iotexproject/iotex-antenna-go/v2/iotex"
 	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/jinzhu/gorm"
+	// sqlite dialects
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
 	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+	"github.com/iotexproject/iotex-hermes/notify"
 )
 
 const (
@@ -33,10 +56,359 @@ func TestGetMinTips(t *testing.T) {
 	require.NoError(err)
 	defer conn.Close()
 
-	c := iotex.NewAuthedClient(iotexapi.NewAPIServiceClient(conn), account)
-
-	os.Setenv("MULTISEND_CONTRACT_ADDRESS", multiSendAddress)
+	c := chain.NewClient(iotexapi.NewAPIServiceClient(conn), account)
 
-	minTips, err := getMinTips(c)
+	cfg := &config.Config{Contracts: config.Contracts{Multisend: multiSendAddress}}
+	minTips, err := getMinTips(context.Background(), cfg, c)
 	require.Equal(minTips.String(), expectedMinTips)
 }
+
+func testAddress(b byte) address.Address {
+	addr, err := address.FromBytes(bytes.Repeat([]byte{b}, 20))
+	if err != nil {
+		panic(err)
+	}
+	return addr
+}
+
+// fakeHermes simulates the hermes, multisend and auto deposit contracts on a FakeClient
+type fakeHermes struct {
+	mu               sync.Mutex
+	client           *chain.FakeClient
+	minTips          *big.Int
+	limit            *big.Int
+	endEpochs        []*big.Int
+	distributedCount map[[32]byte]int
+	committedCount   map[[32]byte]map[uint64]int
+	distributed      map[[32]byte]*big.Int
+	committed        map[[32]byte]map[uint64]*big.Int
+	buckets          map[common.Address]int64
+	forwards         map[common.Address]*ForwardService
+	received         map[common.Address]*big.Int
+	revert           string
+}
+
+func newFakeHermes(cfg *config.Config, c *chain.FakeClient) *fakeHermes {
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		panic(err)
+	}
+	multisendABI, err := abi.JSON(strings.NewReader(MultisendABI))
+	if err != nil {
+		panic(err)
+	}
+	forwardABI, err := abi.JSON(strings.NewReader(ForwardRegistrationABI))
+	if err != nil {
+		panic(err)
+	}
+	hermesAddr, err := ioAddrToEvmAddr(cfg.Contracts.Hermes)
+	if err != nil {
+		panic(err)
+	}
+	forwardAddr := testAddress(13)
+	h := &fakeHermes{
+		client:           c,
+		minTips:          big.NewInt(5),
+		limit:            big.NewInt(100),
+		distributedCount: make(map[[32]byte]int),
+		committedCount:   make(map[[32]byte]map[uint64]int),
+		distributed:      make(map[[32]byte]*big.Int),
+		committed:        make(map[[32]byte]map[uint64]*big.Int),
+		buckets:          make(map[common.Address]int64),
+		forwards:         make(map[common.Address]*ForwardService),
+		received:         make(map[common.Address]*big.Int),
+	}
+	// forwardOf returns the destination of recipient in the window ending at endEpoch, as the contract does
+	forwardOf := func(recipient common.Address, endEpoch *big.Int) common.Address {
+		if f, ok := h.forwards[recipient]; ok && f.Destination != (common.Address{}) && endEpoch.Cmp(f.StartEpoch) >= 0 {
+			return f.Destination
+		}
+		return recipient
+	}
+	c.HandleRead(cfg.Contracts.Multisend, "minTips", func(args []interface{}) ([]interface{}, error) {
+		return []interface{}{h.minTips}, nil
+	})
+	c.HandleRead(cfg.Contracts.Multisend, "limit", func(args []interface{}) ([]interface{}, error) {
+		return []interface{}{h.limit}, nil
+	})
+	c.HandleRead(cfg.Contracts.Hermes, "getEndEpochCount", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return []interface{}{big.NewInt(int64(len(h.endEpochs)))}, nil
+	})
+	c.HandleRead(cfg.Contracts.Hermes, "endEpochs", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return []interface{}{h.endEpochs[args[0].(*big.Int).Int64()]}, nil
+	})
+	c.HandleRead(cfg.Contracts.Hermes, "distributedCount", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return []interface{}{big.NewInt(int64(h.distributedCount[args[0].([32]byte)]))}, nil
+	})
+	c.HandleRead(cfg.Contracts.Hermes, "distributions", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		name, endEpoch := args[0].([32]byte), args[1].(*big.Int).Uint64()
+		amount, ok := h.committed[name][endEpoch]
+		if !ok {
+			amount = big.NewInt(0)
+		}
+		return []interface{}{big.NewInt(int64(h.committedCount[name][endEpoch])), amount}, nil
+	})
+	c.HandleRead(cfg.Contracts.Hermes, "forwardRegistration", func(args []interface{}) ([]interface{}, error) {
+		return []interface{}{common.BytesToAddress(forwardAddr.Bytes())}, nil
+	})
+	c.HandleRead(forwardAddr.String(), "getForwardAddress", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return []interface{}{forwardOf(args[0].(common.Address), args[1].(*big.Int))}, nil
+	})
+	c.HandleRead(forwardAddr.String(), "forwardService", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		f, ok := h.forwards[args[0].(common.Address)]
+		if !ok {
+			f = &ForwardService{Nonce: big.NewInt(0), StartEpoch: big.NewInt(0)}
+		}
+		return []interface{}{f.Nonce, f.Destination, f.StartEpoch}, nil
+	})
+	c.HandleExecute(forwardAddr.String(), "registerForwardService", func(amount *big.Int, args []interface{}) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		owner, destination := args[0].(common.Address), common.BytesToAddress(c.Account().Address().Bytes())
+		h.forwards[owner] = &ForwardService{Nonce: args[1].(*big.Int), Destination: destination, StartEpoch: args[2].(*big.Int)}
+		h.emit(forwardAddr.String(), forwardABI, "RegisterForwardService", [][]byte{owner.Hash().Bytes(),
+			destination.Hash().Bytes()}, args[2].(*big.Int))
+		return nil
+	})
+	c.HandleExecute(forwardAddr.String(), "deregisterForwardService", func(amount *big.Int, args []interface{}) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		owner := args[0].(common.Address)
+		h.forwards[owner] = &ForwardService{Nonce: args[1].(*big.Int), StartEpoch: big.NewInt(0)}
+		h.emit(forwardAddr.String(), forwardABI, "DeregisterForwardService", [][]byte{owner.Hash().Bytes()})
+		return nil
+	})
+	c.HandleRead(cfg.Contracts.AutoDeposit, "bucket", func(args []interface{}) ([]interface{}, error) {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		bucketID, ok := h.buckets[args[0].(common.Address)]
+		if !ok {
+			bucketID = -1
+		}
+		return []interface{}{big.NewInt(bucketID)}, nil
+	})
+	c.HandleExecute(cfg.Contracts.Hermes, "distributeRewards", func(amount *big.Int, args []interface{}) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		name, recipients, amounts := args[0].([32]byte), args[2].([]common.Address), args[3].([]*big.Int)
+		if name == stringToBytes32(h.revert) {
+			h.revert = ""
+			return errors.New("reverted")
+		}
+		total := new(big.Int).Set(h.minTips)
+		for _, a := range amounts {
+			total.Add(total, a)
+		}
+		if total.Cmp(amount) != 0 {
+			return errors.New("msg.value does not match the total amount")
+		}
+		h.distributedCount[name] += len(recipients)
+		if _, ok := h.distributed[name]; !ok {
+			h.distributed[name] = big.NewInt(0)
+		}
+		h.distributed[name].Add(h.distributed[name], new(big.Int).Sub(amount, h.minTips))
+		for i, recipient := range recipients {
+			recipient = forwardOf(recipient, args[1].(*big.Int))
+			if _, ok := h.received[recipient]; !ok {
+				h.received[recipient] = big.NewInt(0)
+			}
+			h.received[recipient].Add(h.received[recipient], amounts[i])
+			h.emit(cfg.Contracts.Multisend, multisendABI, "Transfer", [][]byte{hermesAddr.Hash().Bytes(), recipient.Hash().Bytes()},
+				amounts[i])
+		}
+		h.emit(cfg.Contracts.Hermes, hermesABI, "Distribute", [][]byte{name[:]}, big.NewInt(1), args[1].(*big.Int),
+			big.NewInt(int64(len(recipients))), new(big.Int).Sub(amount, h.minTips))
+		return nil
+	})
+	c.HandleExecute(cfg.Contracts.Hermes, "commitDistributions", func(amount *big.Int, args []interface{}) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		endEpoch := args[0].(*big.Int)
+		for _, name := range args[1].([][32]byte) {
+			if _, ok := h.committedCount[name]; !ok {
+				h.committedCount[name] = make(map[uint64]int)
+			}
+			h.committedCount[name][endEpoch.Uint64()] = h.distributedCount[name]
+			h.distributedCount[name] = 0
+			if _, ok := h.committed[name]; !ok {
+				h.committed[name] = make(map[uint64]*big.Int)
+			}
+			h.committed[name][endEpoch.Uint64()] = big.NewInt(0)
+			if amount, ok := h.distributed[name]; ok {
+				h.committed[name][endEpoch.Uint64()] = amount
+				delete(h.distributed, name)
+			}
+		}
+		h.endEpochs = append(h.endEpochs, endEpoch)
+		h.emit(cfg.Contracts.Hermes, hermesABI, "CommitDistributions", nil, endEpoch, args[1].([][32]byte))
+		return nil
+	})
+	c.HandleReadState("staking", func(request *iotexapi.ReadStateRequest) (*iotexapi.ReadStateResponse, error) {
+		data, err := proto.Marshal(&iotextypes.VoteBucketList{
+			Buckets: []*iotextypes.VoteBucket{{AutoStake: true}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &iotexapi.ReadStateResponse{Data: data}, nil
+	})
+	return h
+}
+
+// emit emits the event of contract with the indexed topics and the non indexed values
+func (h *fakeHermes) emit(contract string, contractABI abi.ABI, name string, topics [][]byte, values ...interface{}) {
+	event := contractABI.Events[name]
+	data, err := event.Inputs.NonIndexed().Pack(values...)
+	if err != nil {
+		panic(err)
+	}
+	id := event.Id()
+	h.client.EmitLog(contract, append([][]byte{id[:]}, topics...), data)
+}
+
+// newAnalyticsServer serves the bookkeeping of the delegates in the first epoch of a window of 24 epochs from 1
+func newAnalyticsServer(delegates ...*mockanalytics.Delegate) *httptest.Server {
+	fixture := &mockanalytics.Fixture{}
+	for epoch := uint64(1); epoch <= 24; epoch++ {
+		e := &mockanalytics.Epoch{Epoch: epoch}
+		if epoch == 1 {
+			e.Delegates = delegates
+		}
+		fixture.Epochs = append(fixture.Epochs, e)
+	}
+	return httptest.NewServer(mockanalytics.NewServer(fixture))
+}
+
+// newTestConfig returns a FakeClient of the test account at height 1000 of epoch 30 with a balance of 1000000000, and
+// a config distributing the bookkeeping served at analyticsURL through the contracts of newFakeHermes in chunks of 10
+// without service fees
+func newTestConfig(require *require.Assertions, analyticsURL string) (*config.Config, *chain.FakeClient) {
+	acc, err := account.HexStringToAccount(testPrivateKey)
+	require.NoError(err)
+	c := chain.NewFakeClient(acc)
+	c.SetChainMeta(1000, 30)
+	c.SetBalance(big.NewInt(1000000000))
+	cfg := &config.Config{
+		AnalyticsEndpoint: analyticsURL,
+		Bookkeeping:       config.Bookkeeping{Source: BookkeepingSourceGraphQL},
+		Contracts: config.Contracts{
+			Hermes:      testAddress(10).String(),
+			Multisend:   testAddress(11).String(),
+			AutoDeposit: testAddress(12).String(),
+		},
+		Gas: config.Gas{Price: config.NewBigInt(big.NewInt(1)), Limit: 100000},
+		Distribution: config.Distribution{
+			ChunkSize:          10,
+			WaiverThreshold:    1,
+			BaseCharge:         config.NewBigInt(big.NewInt(0)),
+			ChargePerRecipient: config.NewBigInt(big.NewInt(0)),
+		},
+		Receipt:  config.Receipt{Timeout: time.Second, Interval: time.Millisecond, MaxInterval: time.Millisecond, Backoff: 1},
+		Schedule: config.Default.Schedule,
+	}
+	return cfg, c
+}
+
+func connectTestDatabase(require *require.Assertions) {
+	gdb, err := gorm.Open("sqlite3", ":memory:")
+	require.NoError(err)
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(err)
+	require.NoError(dao.SetDatabase(gdb, priv, &priv.PublicKey))
+}
+
+func TestReward(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	voters := []address.Address{testAddress(1), testAddress(2), testAddress(3)}
+	analytics := newAnalyticsServer(
+		&mockanalytics.Delegate{
+			DelegateName:   "alpha",
+			StakingAddress: voters[0].String(),
+			Refund:         "1000",
+			Rewards: []*mockanalytics.Reward{
+				{Voter: voters[0].String(), Amount: "100000"},
+				{Voter: voters[1].String(), Amount: "200000"},
+				{Voter: voters[2].String(), Amount: "300000"},
+			},
+		},
+		&mockanalytics.Delegate{
+			DelegateName:   "beta",
+			StakingAddress: voters[1].String(),
+			Refund:         "0",
+			Rewards:        []*mockanalytics.Reward{{Voter: voters[1].String(), Amount: "400000"}},
+		},
+	)
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.ChunkSize = 2
+	cfg.Distribution.WaiverThreshold = 5
+	cfg.Distribution.BaseCharge = config.NewBigInt(big.NewInt(100))
+	cfg.Distribution.ChargePerRecipient = config.NewBigInt(big.NewInt(10))
+	hermes := newFakeHermes(cfg, c)
+	hermes.buckets[common.BytesToAddress(voters[2].Bytes())] = 7
+
+	cycle := &dao.Cycle{StartEpoch: 1, EndEpoch: 24, Status: dao.CycleClaimed}
+	require.NoError(cycle.Save(nil))
+
+	sink := notify.NewSink()
+	alerts := httptest.NewServer(sink)
+	defer alerts.Close()
+	notify.SetNotifiers(notify.NewWebhook(alerts.URL))
+	defer notify.SetNotifiers()
+
+	// the distribution stops at the second delegate and resumes from it
+	hermes.revert = "beta"
+	require.Error(Reward(context.Background(), cfg, c, cycle))
+	events := sink.Events()
+	require.Len(events, 1)
+	require.Equal(notify.EventDistributeFailed, events[0].Type)
+	require.Equal(uint64(24), events[0].EndEpoch)
+	require.Equal("beta", events[0].Delegate)
+	require.Equal(dao.CycleDistributing, cycle.Status)
+	require.Equal(1, cycle.DistributedDelegates)
+	require.NoError(Reward(context.Background(), cfg, c, cycle))
+	require.Equal(dao.CycleCommitted, cycle.Status)
+	require.Equal(2, cycle.DistributedDelegates)
+	require.Len(hermes.endEpochs, 1)
+	require.Equal(uint64(24), hermes.endEpochs[0].Uint64())
+
+	// alpha pays a service fee of 130 out of its refund, the auto deposit voter gets zero on chain
+	received := func(addr address.Address) string {
+		return hermes.received[common.BytesToAddress(addr.Bytes())].String()
+	}
+	require.Equal("100870", received(voters[0]))
+	require.Equal("600000", received(voters[1]))
+	require.Equal("0", received(voters[2]))
+	records, err := dao.FindNewDropRecordByLimit(10)
+	require.NoError(err)
+	require.Len(records, 1)
+	require.Equal(voters[2].String(), records[0].Voter)
+	require.Equal("300000", records[0].Amount)
+	require.Equal(uint64(7), records[0].Index)
+
+	NewSender(cfg, c).Send(context.Background())
+	records, err = dao.FindNewDropRecordByLimit(10)
+	require.NoError(err)
+	require.Len(records, 0)
+	actions := c.Actions()
+	deposit := actions[len(actions)-1]
+	require.Equal(chain.ActionDeposit, deposit.Type)
+	require.Equal(uint64(7), deposit.BucketID)
+	require.Equal("290000", deposit.Amount.String())
+	require.Equal([]string{notify.EventDistributeFailed}, sink.Types())
+}
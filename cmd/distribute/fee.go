@@ -0,0 +1,173 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// FeePolicyWaived is the fee policy recorded for a delegate whose service fee is waived
+const FeePolicyWaived = "waived"
+
+// FeePolicy computes the service fee charged to a delegate from the number of its voters and its reward, the total
+// of the rewards of its voters and its refund
+type FeePolicy interface {
+	Fee(voterCount int64, reward *big.Int) *big.Int
+}
+
+// FlatFee charges the same fee to every delegate
+type FlatFee struct {
+	Amount *big.Int
+}
+
+// Fee implements FeePolicy
+func (p *FlatFee) Fee(voterCount int64, reward *big.Int) *big.Int {
+	return new(big.Int).Set(p.Amount)
+}
+
+// LinearFee charges a base fee and a fee per voter
+type LinearFee struct {
+	Base         *big.Int
+	PerRecipient *big.Int
+}
+
+// Fee implements FeePolicy
+func (p *LinearFee) Fee(voterCount int64, reward *big.Int) *big.Int {
+	fee := new(big.Int).Mul(big.NewInt(voterCount), p.PerRecipient)
+	return fee.Add(fee, p.Base)
+}
+
+// PercentageFee charges the basis points of the reward
+type PercentageFee struct {
+	BasisPoints uint64
+}
+
+// Fee implements FeePolicy
+func (p *PercentageFee) Fee(voterCount int64, reward *big.Int) *big.Int {
+	fee := new(big.Int).Mul(reward, new(big.Int).SetUint64(p.BasisPoints))
+	return fee.Div(fee, big.NewInt(10000))
+}
+
+// FeeTier is the fee of the delegates with at least MinRecipients voters
+type FeeTier struct {
+	MinRecipients int64
+	Fee           *big.Int
+}
+
+// TieredFee charges the fee of the last tier whose minimum the voter count reaches, nothing below the first tier
+type TieredFee struct {
+	Tiers []FeeTier
+}
+
+// Fee implements FeePolicy
+func (p *TieredFee) Fee(voterCount int64, reward *big.Int) *big.Int {
+	fee := big.NewInt(0)
+	for _, tier := range p.Tiers {
+		if voterCount < tier.MinRecipients {
+			break
+		}
+		fee.Set(tier.Fee)
+	}
+	return fee
+}
+
+// CappedFee charges the fee of Policy, at most Max
+type CappedFee struct {
+	Policy FeePolicy
+	Max    *big.Int
+}
+
+// Fee implements FeePolicy
+func (p *CappedFee) Fee(voterCount int64, reward *big.Int) *big.Int {
+	fee := p.Policy.Fee(voterCount, reward)
+	if fee.Cmp(p.Max) > 0 {
+		return new(big.Int).Set(p.Max)
+	}
+	return fee
+}
+
+// NewFeePolicy returns the fee policy defined by cfg
+func NewFeePolicy(cfg *config.FeePolicy) (FeePolicy, error) {
+	switch cfg.Type {
+	case "flat":
+		return &FlatFee{Amount: cfg.Amount.Int()}, nil
+	case "linear":
+		return &LinearFee{Base: cfg.Base.Int(), PerRecipient: cfg.PerRecipient.Int()}, nil
+	case "percentage":
+		return &PercentageFee{BasisPoints: cfg.BasisPoints}, nil
+	case "tiered":
+		tiers := make([]FeeTier, 0, len(cfg.Tiers))
+		for _, tier := range cfg.Tiers {
+			tiers = append(tiers, FeeTier{MinRecipients: tier.MinRecipients, Fee: tier.Fee.Int()})
+		}
+		return &TieredFee{Tiers: tiers}, nil
+	case "capped":
+		if cfg.Policy == nil {
+			return nil, errors.New("capped fee policy without a policy")
+		}
+		policy, err := NewFeePolicy(cfg.Policy)
+		if err != nil {
+			return nil, err
+		}
+		return &CappedFee{Policy: policy, Max: cfg.Max.Int()}, nil
+	default:
+		return nil, fmt.Errorf("unknown fee policy %s", cfg.Type)
+	}
+}
+
+// feePolicyOf returns the type and the fee policy of the delegate
+func feePolicyOf(cfg *config.Config, delegateName string) (string, FeePolicy, error) {
+	policy, ok := cfg.Distribution.FeePolicies[delegateName]
+	if !ok {
+		policy = cfg.Distribution.FeePolicy
+	}
+	if policy.Type == "" {
+		policy = config.FeePolicy{
+			Type:         "linear",
+			Base:         cfg.Distribution.BaseCharge,
+			PerRecipient: cfg.Distribution.ChargePerRecipient,
+		}
+	}
+	p, err := NewFeePolicy(&policy)
+	if err != nil {
+		return "", nil, err
+	}
+	return policy.Type, p, nil
+}
+
+// chargeServiceFee charges the service fee of policy to the refund of a delegate, at most the whole refund, and
+// returns the fee and the refund left
+func chargeServiceFee(policy FeePolicy, voterCount int64, reward *big.Int, refund *big.Int) (*big.Int, *big.Int) {
+	serviceFee := policy.Fee(voterCount, reward)
+	if serviceFee.Cmp(refund) > 0 {
+		return new(big.Int).Set(refund), big.NewInt(0)
+	}
+	return serviceFee, new(big.Int).Sub(refund, serviceFee)
+}
+
+// saveServiceFees records the service fee of every delegate of plan in its cycle
+func saveServiceFees(plan *Plan) error {
+	for _, d := range plan.Delegates {
+		fee := &dao.ServiceFee{
+			EndEpoch:     plan.EndEpoch,
+			DelegateName: d.DelegateName,
+			Policy:       d.FeePolicy,
+			Fee:          d.ServiceFee,
+			Refund:       d.Refund,
+		}
+		if err := fee.Save(nil); err != nil {
+			return errors.Wrap(err, "failed to save service fee")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+func TestFeePolicy(t *testing.T) {
+	require := require.New(t)
+
+	bigInt := func(v int64) config.BigInt {
+		return config.NewBigInt(big.NewInt(v))
+	}
+	tiers := []config.FeeTier{{MinRecipients: 10, Fee: bigInt(100)}, {MinRecipients: 100, Fee: bigInt(500)}}
+	for _, test := range []struct {
+		policy     config.FeePolicy
+		voterCount int64
+		fee        int64
+	}{
+		{config.FeePolicy{Type: "flat", Amount: bigInt(30)}, 50, 30},
+		{config.FeePolicy{Type: "linear", Base: bigInt(10), PerRecipient: bigInt(2)}, 50, 110},
+		{config.FeePolicy{Type: "percentage", BasisPoints: 250}, 50, 250},
+		{config.FeePolicy{Type: "tiered", Tiers: tiers}, 9, 0},
+		{config.FeePolicy{Type: "tiered", Tiers: tiers}, 50, 100},
+		{config.FeePolicy{Type: "tiered", Tiers: tiers}, 100, 500},
+		{config.FeePolicy{Type: "capped", Max: bigInt(200), Policy: &config.FeePolicy{Type: "percentage", BasisPoints: 250}}, 50, 200},
+		{config.FeePolicy{Type: "capped", Max: bigInt(200), Policy: &config.FeePolicy{Type: "flat", Amount: bigInt(30)}}, 50, 30},
+	} {
+		policy, err := NewFeePolicy(&test.policy)
+		require.NoError(err)
+		require.Equal(big.NewInt(test.fee), policy.Fee(test.voterCount, big.NewInt(10000)), test.policy.Type)
+	}
+	_, err := NewFeePolicy(&config.FeePolicy{Type: "unknown"})
+	require.Error(err)
+
+	// the fee is charged to the refund, at most the whole refund
+	fee, refund := chargeServiceFee(&FlatFee{Amount: big.NewInt(30)}, 1, big.NewInt(100), big.NewInt(50))
+	require.Equal(big.NewInt(30), fee)
+	require.Equal(big.NewInt(20), refund)
+	fee, refund = chargeServiceFee(&FlatFee{Amount: big.NewInt(80)}, 1, big.NewInt(100), big.NewInt(50))
+	require.Equal(big.NewInt(50), fee)
+	require.Equal(big.NewInt(0), refund)
+
+	// a delegate is charged by its own policy, else by the default one, else by the linear charges
+	cfg := &config.Config{Distribution: config.Distribution{
+		BaseCharge:         bigInt(10),
+		ChargePerRecipient: bigInt(1),
+		FeePolicies:        map[string]config.FeePolicy{"alpha": {Type: "percentage", BasisPoints: 1000}},
+	}}
+	bookkeeping := &Bookkeeping{StartEpoch: 1, Delegates: []*DelegateBookkeeping{
+		{
+			DelegateName:   "alpha",
+			StakingAddress: testAddress(1).String(),
+			VoterCount:     2,
+			Refund:         "1000",
+			Rewards:        []*VoterReward{{Voter: testAddress(2).String(), Amount: "3000"}},
+		},
+		{
+			DelegateName:   "beta",
+			StakingAddress: testAddress(3).String(),
+			VoterCount:     2,
+			Refund:         "1000",
+			Rewards:        []*VoterReward{{Voter: testAddress(4).String(), Amount: "3000"}},
+		},
+		{
+			DelegateName:    "gamma",
+			StakingAddress:  testAddress(5).String(),
+			VoterCount:      2,
+			WaiveServiceFee: true,
+			Refund:          "1000",
+			Rewards:         []*VoterReward{{Voter: testAddress(6).String(), Amount: "3000"}},
+		},
+	}}
+	distributions, err := distributionsOf(cfg, bookkeeping, nil)
+	require.NoError(err)
+	var fees []string
+	for _, dist := range distributions {
+		fees = append(fees, dist.FeePolicy+" "+dist.ServiceFee.String()+" "+dist.Refund.String())
+	}
+	require.Equal([]string{"percentage 400 600", "linear 12 988", "waived 0 1000"}, fees)
+
+	cfg.Distribution.FeePolicy = config.FeePolicy{Type: "flat", Amount: bigInt(5)}
+	distributions, err = distributionsOf(cfg, bookkeeping, nil)
+	require.NoError(err)
+	require.Equal("flat", distributions[1].FeePolicy)
+	require.Equal(big.NewInt(5), distributions[1].ServiceFee)
+
+	// the fees are recorded in the cycle of the plan
+	connectTestDatabase(require)
+	require.NoError(saveServiceFees(newPlan(24, distributions, 10)))
+	require.NoError(saveServiceFees(newPlan(24, distributions, 10)))
+	records, err := dao.FindServiceFeesByEndEpoch(24)
+	require.NoError(err)
+	require.Len(records, 3)
+	require.Equal("alpha", records[0].DelegateName)
+	require.Equal("percentage", records[0].Policy)
+	require.Equal("400", records[0].Fee)
+	require.Equal("600", records[0].Refund)
+}
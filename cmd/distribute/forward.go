@@ -0,0 +1,355 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// Types of a forward event
+const (
+	ForwardEventRegister   = "register"
+	ForwardEventDeregister = "deregister"
+)
+
+// ForwardCmd is the forward command
+var ForwardCmd = &cobra.Command{
+	Use:   "forward",
+	Short: "Query the forward addresses the Hermes contract pays the rewards of its recipients to",
+}
+
+var forwardAddressCmd = &cobra.Command{
+	Use:   "address VOTER...",
+	Short: "Print the address the rewards of every voter are paid to in a window",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		ctx := context.Background()
+
+		endEpoch := forwardEndEpoch
+		if endEpoch == 0 {
+			window, err := NextWindow(ctx, cfg, c)
+			if err != nil {
+				return err
+			}
+			endEpoch = window.EndEpoch
+		}
+		fr, err := NewForwardRegistration(ctx, cfg, c)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "VOTER\tEND EPOCH\tPAID TO\tREGISTERED\tSTART EPOCH\n")
+		for _, voter := range args {
+			addr, err := address.FromString(voter)
+			if err != nil {
+				return fmt.Errorf("invalid voter %s: %v", voter, err)
+			}
+			owner := common.BytesToAddress(addr.Bytes())
+			paidTo, err := fr.ForwardAddress(ctx, owner, endEpoch)
+			if err != nil {
+				return err
+			}
+			service, err := fr.ForwardService(ctx, owner)
+			if err != nil {
+				return err
+			}
+			registered, startEpoch := "-", "-"
+			if service.Destination != (common.Address{}) {
+				if registered, err = evmAddrToIoAddr(service.Destination); err != nil {
+					return err
+				}
+				startEpoch = service.StartEpoch.String()
+			}
+			paidToAddr, err := evmAddrToIoAddr(paidTo)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", voter, endEpoch, paidToAddr, registered, startEpoch)
+		}
+		return tw.Flush()
+	},
+}
+
+var forwardEventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List the forward registrations and deregistrations in a range of blocks",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		ctx := context.Background()
+
+		owners := make([]common.Address, 0, len(forwardOwners))
+		for _, owner := range forwardOwners {
+			addr, err := address.FromString(owner)
+			if err != nil {
+				return fmt.Errorf("invalid owner %s: %v", owner, err)
+			}
+			owners = append(owners, common.BytesToAddress(addr.Bytes()))
+		}
+		fr, err := NewForwardRegistration(ctx, cfg, c)
+		if err != nil {
+			return err
+		}
+		events, err := fr.Events(ctx, forwardFromHeight, forwardToHeight, owners)
+		if err != nil {
+			return err
+		}
+		switch forwardOutput {
+		case "json":
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(events)
+		case "table":
+			return WriteForwardEvents(cmd.OutOrStdout(), events)
+		default:
+			return fmt.Errorf("unknown output format %s", forwardOutput)
+		}
+	},
+}
+
+var (
+	forwardEndEpoch   uint64
+	forwardOwners     []string
+	forwardFromHeight uint64
+	forwardToHeight   uint64
+	forwardOutput     string
+)
+
+func init() {
+	forwardAddressCmd.Flags().Uint64Var(&forwardEndEpoch, "end-epoch", 0, "end epoch of the window, the next window if 0")
+	forwardEventsCmd.Flags().StringSliceVar(&forwardOwners, "owner", nil, "owners to list, all if empty")
+	forwardEventsCmd.Flags().Uint64Var(&forwardFromHeight, "from-height", 1, "first block height to list")
+	forwardEventsCmd.Flags().Uint64Var(&forwardToHeight, "to-height", 0, "last block height to list, the tip of the chain if 0")
+	forwardEventsCmd.Flags().StringVarP(&forwardOutput, "output", "o", "table", "output format, table or json")
+	ForwardCmd.AddCommand(forwardAddressCmd, forwardEventsCmd)
+}
+
+// ForwardService is the forward registered by an owner, with a zero destination if there is none
+type ForwardService struct {
+	Nonce       *big.Int
+	Destination common.Address
+	StartEpoch  *big.Int
+}
+
+// ForwardEvent is a RegisterForwardService or DeregisterForwardService event. Alternative and Epoch are the
+// destination and the start epoch of a registration.
+type ForwardEvent struct {
+	Type        string `json:"type"`
+	Owner       string `json:"owner"`
+	Alternative string `json:"alternative,omitempty"`
+	Epoch       uint64 `json:"epoch,omitempty"`
+	Height      uint64 `json:"height"`
+	ActionHash  string `json:"actionHash"`
+}
+
+// ForwardRegistration reads the ForwardRegistration contract, by which the Hermes contract pays the rewards of a
+// recipient to the destination it registers
+type ForwardRegistration struct {
+	client   chain.Client
+	contract address.Address
+	abi      abi.ABI
+}
+
+// NewForwardRegistration returns the ForwardRegistration of cfg.Contracts.ForwardRegistration, or the one the Hermes
+// contract calls if it is not set
+func NewForwardRegistration(ctx context.Context, cfg *config.Config, c chain.Client) (*ForwardRegistration, error) {
+	forwardABI, err := abi.JSON(strings.NewReader(ForwardRegistrationABI))
+	if err != nil {
+		return nil, err
+	}
+	contract := cfg.Contracts.ForwardRegistration
+	if contract == "" {
+		hermes, err := address.FromString(cfg.Contracts.Hermes)
+		if err != nil {
+			return nil, err
+		}
+		hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+		if err != nil {
+			return nil, err
+		}
+		data, err := c.ReadContract(ctx, hermes, hermesABI, "forwardRegistration")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read forward registration contract")
+		}
+		var addr common.Address
+		if err := data.Unmarshal(&addr); err != nil {
+			return nil, err
+		}
+		if contract, err = evmAddrToIoAddr(addr); err != nil {
+			return nil, err
+		}
+	}
+	addr, err := address.FromString(contract)
+	if err != nil {
+		return nil, err
+	}
+	return &ForwardRegistration{client: c, contract: addr, abi: forwardABI}, nil
+}
+
+// Address returns the address of the contract
+func (f *ForwardRegistration) Address() address.Address {
+	return f.contract
+}
+
+// ForwardAddress returns the address the rewards of owner in the window ending at endEpoch are paid to, owner itself
+// if it registers no forward starting by then
+func (f *ForwardRegistration) ForwardAddress(ctx context.Context, owner common.Address, endEpoch uint64) (common.Address, error) {
+	data, err := f.client.ReadContract(ctx, f.contract, f.abi, "getForwardAddress", owner,
+		new(big.Int).SetUint64(endEpoch))
+	if err != nil {
+		return common.Address{}, err
+	}
+	var forward common.Address
+	if err := data.Unmarshal(&forward); err != nil {
+		return common.Address{}, err
+	}
+	return forward, nil
+}
+
+// ForwardService returns the forward registered by owner
+func (f *ForwardRegistration) ForwardService(ctx context.Context, owner common.Address) (*ForwardService, error) {
+	data, err := f.client.ReadContract(ctx, f.contract, f.abi, "forwardService", owner)
+	if err != nil {
+		return nil, err
+	}
+	service := &ForwardService{}
+	if err := data.Unmarshal(service); err != nil {
+		return nil, err
+	}
+	return service, nil
+}
+
+// Events returns the forward events of owners, of all the owners if it is empty, from fromHeight to toHeight, to the
+// tip of the chain if it is 0
+func (f *ForwardRegistration) Events(ctx context.Context, fromHeight, toHeight uint64, owners []common.Address) ([]*ForwardEvent, error) {
+	if toHeight == 0 {
+		meta, err := f.client.GetChainMeta(ctx)
+		if err != nil {
+			return nil, err
+		}
+		toHeight = meta.Height
+	}
+	registerID, deregisterID := f.abi.Events["RegisterForwardService"].Id(), f.abi.Events["DeregisterForwardService"].Id()
+	filter := &iotexapi.LogsFilter{
+		Address: []string{f.contract.String()},
+		Topics:  []*iotexapi.Topics{{Topic: [][]byte{registerID[:], deregisterID[:]}}},
+	}
+	selected := make(map[common.Address]bool, len(owners))
+	for _, owner := range owners {
+		selected[owner] = true
+	}
+
+	var events []*ForwardEvent
+	for from := fromHeight; from <= toHeight; from += reconcileBlocks {
+		count := uint64(reconcileBlocks)
+		if toHeight-from+1 < count {
+			count = toHeight - from + 1
+		}
+		logs, err := f.client.GetLogs(ctx, filter, from, count)
+		if err != nil {
+			return nil, err
+		}
+		for _, log := range logs {
+			event, err := f.eventOf(log)
+			if err != nil {
+				return nil, err
+			}
+			if len(selected) > 0 && !selected[common.BytesToAddress(log.Topics[1])] {
+				continue
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// eventOf returns the forward event of a log
+func (f *ForwardRegistration) eventOf(log *iotextypes.Log) (*ForwardEvent, error) {
+	if len(log.Topics) < 2 {
+		return nil, errors.New("invalid topics of forward event")
+	}
+	owner, err := evmAddrToIoAddr(common.BytesToAddress(log.Topics[1]))
+	if err != nil {
+		return nil, err
+	}
+	event := &ForwardEvent{
+		Owner:      owner,
+		Height:     log.BlkHeight,
+		ActionHash: hex.EncodeToString(log.ActHash),
+	}
+	registerID := f.abi.Events["RegisterForwardService"].Id()
+	if string(log.Topics[0]) != string(registerID[:]) {
+		event.Type = ForwardEventDeregister
+		return event, nil
+	}
+	if len(log.Topics) < 3 {
+		return nil, errors.New("invalid topics of register forward event")
+	}
+	var registration struct {
+		Epoch *big.Int
+	}
+	if err := f.abi.Unpack(&registration, "RegisterForwardService", log.Data); err != nil {
+		return nil, err
+	}
+	if event.Alternative, err = evmAddrToIoAddr(common.BytesToAddress(log.Topics[2])); err != nil {
+		return nil, err
+	}
+	event.Type = ForwardEventRegister
+	event.Epoch = registration.Epoch.Uint64()
+	return event, nil
+}
+
+// WriteForwardEvents writes the forward events as a table
+func WriteForwardEvents(w io.Writer, events []*ForwardEvent) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HEIGHT\tTYPE\tOWNER\tALTERNATIVE\tEPOCH\tACTION HASH")
+	for _, e := range events {
+		alternative, epoch := "-", "-"
+		if e.Type == ForwardEventRegister {
+			alternative, epoch = e.Alternative, fmt.Sprintf("%d", e.Epoch)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", e.Height, e.Type, e.Owner, alternative, epoch, e.ActionHash)
+	}
+	return tw.Flush()
+}
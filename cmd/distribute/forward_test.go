@@ -0,0 +1,102 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestForwardRegistration(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100000"},
+			{Voter: testAddress(2).String(), Amount: "200000"},
+		},
+	})
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	newFakeHermes(cfg, c)
+	ctx := context.Background()
+
+	// the contract is the one the Hermes contract calls
+	fr, err := NewForwardRegistration(ctx, cfg, c)
+	require.NoError(err)
+	require.Equal(testAddress(13).String(), fr.Address().String())
+
+	// the vault registers as the forward of voter 2 from epoch 24, and of voter 3 until it deregisters
+	forwardABI, err := abi.JSON(strings.NewReader(ForwardRegistrationABI))
+	require.NoError(err)
+	voter2, voter3 := common.BytesToAddress(testAddress(2).Bytes()), common.BytesToAddress(testAddress(3).Bytes())
+	vault := common.BytesToAddress(c.Account().Address().Bytes())
+	gas := chain.Gas{Price: big.NewInt(1), Limit: 100000}
+	for _, call := range [][]interface{}{
+		{"registerForwardService", voter2, big.NewInt(1), big.NewInt(24), []byte{}},
+		{"registerForwardService", voter3, big.NewInt(1), big.NewInt(1), []byte{}},
+		{"deregisterForwardService", voter3, big.NewInt(2), []byte{}},
+	} {
+		_, err := c.ExecuteContract(ctx, fr.Address(), forwardABI, big.NewInt(0), gas, call[0].(string), call[1:]...)
+		require.NoError(err)
+	}
+
+	forward, err := fr.ForwardAddress(ctx, voter2, 23)
+	require.NoError(err)
+	require.Equal(voter2, forward)
+	forward, err = fr.ForwardAddress(ctx, voter2, 24)
+	require.NoError(err)
+	require.Equal(vault, forward)
+	forward, err = fr.ForwardAddress(ctx, voter3, 24)
+	require.NoError(err)
+	require.Equal(voter3, forward)
+	service, err := fr.ForwardService(ctx, voter2)
+	require.NoError(err)
+	require.Equal(vault, service.Destination)
+	require.Equal(big.NewInt(24), service.StartEpoch)
+
+	events, err := fr.Events(ctx, 1, 0, nil)
+	require.NoError(err)
+	require.Len(events, 3)
+	require.Equal(&ForwardEvent{Type: ForwardEventRegister, Owner: testAddress(2).String(),
+		Alternative: c.Account().Address().String(), Epoch: 24, Height: 1000, ActionHash: events[0].ActionHash}, events[0])
+	events, err = fr.Events(ctx, 1, 0, []common.Address{voter3})
+	require.NoError(err)
+	require.Len(events, 2)
+	require.Equal(ForwardEventRegister, events[0].Type)
+	require.Equal(ForwardEventDeregister, events[1].Type)
+	require.Equal(testAddress(3).String(), events[1].Owner)
+	events, err = fr.Events(ctx, 1, 999, nil)
+	require.NoError(err)
+	require.Empty(events)
+
+	// the dry run shows the destination of a forwarded recipient
+	report, err := Simulate(ctx, cfg, c)
+	require.NoError(err)
+	forwards := make(map[string]string)
+	for _, recipient := range report.Delegates[0].Chunks[0].Recipients {
+		forwards[recipient.Address] = recipient.ForwardAddress
+	}
+	require.Equal(map[string]string{testAddress(1).String(): "", testAddress(2).String(): c.Account().Address().String()}, forwards)
+	var table bytes.Buffer
+	require.NoError(report.WriteTable(&table))
+	require.Contains(table.String(), c.Account().Address().String())
+}
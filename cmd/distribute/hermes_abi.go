@@ -944,4 +944,139 @@ const (
         "stateMutability": "view",
         "type": "function"
     }]`
+
+	// ForwardRegistrationABI defines the ABI of the forward registration contract
+	ForwardRegistrationABI = `[
+    {
+        "constant": true,
+        "inputs": [
+            {
+                "name": "",
+                "type": "address"
+            }
+        ],
+        "name": "forwardService",
+        "outputs": [
+            {
+                "name": "nonce",
+                "type": "uint256"
+            },
+            {
+                "name": "destination",
+                "type": "address"
+            },
+            {
+                "name": "startEpoch",
+                "type": "uint256"
+            }
+        ],
+        "payable": false,
+        "stateMutability": "view",
+        "type": "function"
+    },
+    {
+        "anonymous": false,
+        "inputs": [
+            {
+                "indexed": true,
+                "name": "owner",
+                "type": "address"
+            },
+            {
+                "indexed": true,
+                "name": "alternative",
+                "type": "address"
+            },
+            {
+                "indexed": false,
+                "name": "epoch",
+                "type": "uint256"
+            }
+        ],
+        "name": "RegisterForwardService",
+        "type": "event"
+    },
+    {
+        "anonymous": false,
+        "inputs": [
+            {
+                "indexed": true,
+                "name": "owner",
+                "type": "address"
+            }
+        ],
+        "name": "DeregisterForwardService",
+        "type": "event"
+    },
+    {
+        "constant": false,
+        "inputs": [
+            {
+                "name": "owner",
+                "type": "address"
+            },
+            {
+                "name": "nonce",
+                "type": "uint256"
+            },
+            {
+                "name": "startEpoch",
+                "type": "uint256"
+            },
+            {
+                "name": "signature",
+                "type": "bytes"
+            }
+        ],
+        "name": "registerForwardService",
+        "outputs": [],
+        "payable": false,
+        "stateMutability": "nonpayable",
+        "type": "function"
+    },
+    {
+        "constant": false,
+        "inputs": [
+            {
+                "name": "owner",
+                "type": "address"
+            },
+            {
+                "name": "nonce",
+                "type": "uint256"
+            },
+            {
+                "name": "signature",
+                "type": "bytes"
+            }
+        ],
+        "name": "deregisterForwardService",
+        "outputs": [],
+        "payable": false,
+        "stateMutability": "nonpayable",
+        "type": "function"
+    },
+    {
+        "constant": true,
+        "inputs": [
+            {
+                "name": "owner",
+                "type": "address"
+            },
+            {
+                "name": "endEpoch",
+                "type": "uint256"
+            }
+        ],
+        "name": "getForwardAddress",
+        "outputs": [
+            {
+                "name": "",
+                "type": "address"
+            }
+        ],
+        "payable": false,
+        "stateMutability": "view",
+        "type": "function"
+    }]`
 )
@@ -0,0 +1,236 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// HistoryCmd is the history command
+var HistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the committed windows with the recipient count and amount of every delegate",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+
+		h, err := GetHistory(context.Background(), cfg, c, &historyFilter)
+		if err != nil {
+			return err
+		}
+		switch historyOutput {
+		case "json":
+			return h.WriteJSON(cmd.OutOrStdout())
+		case "csv":
+			return h.WriteCSV(cmd.OutOrStdout())
+		case "table":
+			return h.WriteTable(cmd.OutOrStdout())
+		default:
+			return fmt.Errorf("unknown output format %s", historyOutput)
+		}
+	},
+}
+
+var (
+	historyFilter HistoryFilter
+	historyOutput string
+)
+
+func init() {
+	HistoryCmd.Flags().StringSliceVar(&historyFilter.Delegates, "delegate", nil, "delegates to list, all the committed delegates if empty")
+	HistoryCmd.Flags().Uint64Var(&historyFilter.FromEpoch, "from-epoch", 0, "first end epoch to list")
+	HistoryCmd.Flags().Uint64Var(&historyFilter.ToEpoch, "to-epoch", 0, "last end epoch to list, the last committed one if 0")
+	HistoryCmd.Flags().StringVarP(&historyOutput, "output", "o", "table", "output format, table, csv or json")
+}
+
+// HistoryFilter selects the windows and delegates of a history
+type HistoryFilter struct {
+	// Delegates are the delegates to list, all the delegates committed in a window if empty
+	Delegates []string
+	// FromEpoch and ToEpoch bound the end epochs of the windows, ToEpoch only if positive
+	FromEpoch uint64
+	ToEpoch   uint64
+}
+
+// History is the committed windows of the hermes contract
+type History struct {
+	Windows []*WindowHistory `json:"windows"`
+}
+
+// WindowHistory is the distributions committed in the window ending at EndEpoch
+type WindowHistory struct {
+	EndEpoch  uint64             `json:"endEpoch"`
+	Delegates []*DelegateHistory `json:"delegates"`
+}
+
+// DelegateHistory is the number of recipients of a delegate in a window and the amount distributed to them
+type DelegateHistory struct {
+	DelegateName string `json:"delegateName"`
+	Count        uint64 `json:"count"`
+	Amount       string `json:"amount"`
+}
+
+// GetHistory reads the committed windows matching filter from the endEpochs and distributions of the hermes contract.
+// Without a delegate filter, the delegates of a window are read from its persisted plan, or from its
+// CommitDistributions event if the window has no plan.
+func GetHistory(ctx context.Context, cfg *config.Config, c chain.Client, filter *HistoryFilter) (*History, error) {
+	endEpochCount, err := getEndEpochCount(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	h := &History{Windows: []*WindowHistory{}}
+	for i := uint64(0); i < endEpochCount; i++ {
+		endEpoch, err := getEndEpoch(ctx, cfg, c, i)
+		if err != nil {
+			return nil, err
+		}
+		if endEpoch < filter.FromEpoch {
+			continue
+		}
+		// the end epochs are committed in increasing order
+		if filter.ToEpoch > 0 && endEpoch > filter.ToEpoch {
+			break
+		}
+		names := filter.Delegates
+		if len(names) == 0 {
+			if names, err = committedDelegates(ctx, cfg, c, endEpoch); err != nil {
+				return nil, err
+			}
+		}
+		window := &WindowHistory{EndEpoch: endEpoch, Delegates: []*DelegateHistory{}}
+		for _, name := range names {
+			count, amount, err := getCommittedDistribution(ctx, cfg, c, name, endEpoch)
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				continue
+			}
+			window.Delegates = append(window.Delegates, &DelegateHistory{
+				DelegateName: name,
+				Count:        count,
+				Amount:       amount.String(),
+			})
+		}
+		if len(filter.Delegates) > 0 && len(window.Delegates) == 0 {
+			continue
+		}
+		h.Windows = append(h.Windows, window)
+	}
+	return h, nil
+}
+
+// committedDelegates returns the names of the delegates committed in the window ending at endEpoch
+func committedDelegates(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) ([]string, error) {
+	stored, err := dao.FindPlanByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		plan := &Plan{}
+		if err := json.Unmarshal([]byte(stored.Content), plan); err != nil {
+			return nil, errors.Wrap(err, "failed to parse distribution plan")
+		}
+		names := make([]string, 0, len(plan.Delegates))
+		for _, d := range plan.Delegates {
+			names = append(names, d.DelegateName)
+		}
+		return names, nil
+	}
+
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		return nil, err
+	}
+	commitID := hermesABI.Events["CommitDistributions"].Id()
+	filter := &iotexapi.LogsFilter{
+		Address: []string{cfg.Contracts.Hermes},
+		Topics:  []*iotexapi.Topics{{Topic: [][]byte{commitID[:]}}},
+	}
+	var names []string
+	err = scanWindowLogs(ctx, c, endEpoch, filter, func(log *iotextypes.Log) (bool, error) {
+		if names != nil {
+			return true, nil
+		}
+		committed, err := committedNamesOf(hermesABI, log, endEpoch)
+		names = committed
+		return names != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if names == nil {
+		return nil, fmt.Errorf("no commit of end epoch %d found", endEpoch)
+	}
+	return names, nil
+}
+
+// WriteJSON writes the history as indented JSON
+func (h *History) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(h)
+}
+
+// WriteCSV writes the history as CSV, a row per delegate of every window
+func (h *History) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"endEpoch", "delegateName", "count", "amount"}); err != nil {
+		return err
+	}
+	for _, window := range h.Windows {
+		for _, d := range window.Delegates {
+			row := []string{strconv.FormatUint(window.EndEpoch, 10), d.DelegateName, strconv.FormatUint(d.Count, 10), d.Amount}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTable writes the history as a human readable table
+func (h *History) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "END EPOCH\tDELEGATE\tCOUNT\tAMOUNT")
+	for _, window := range h.Windows {
+		for _, d := range window.Delegates {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", window.EndEpoch, d.DelegateName, d.Count, d.Amount)
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestHistory(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(
+		&mockanalytics.Delegate{
+			DelegateName:   "alpha",
+			StakingAddress: testAddress(1).String(),
+			Refund:         "0",
+			Rewards: []*mockanalytics.Reward{
+				{Voter: testAddress(1).String(), Amount: "100"},
+				{Voter: testAddress(2).String(), Amount: "200"},
+				{Voter: testAddress(3).String(), Amount: "300"},
+			},
+		},
+		&mockanalytics.Delegate{
+			DelegateName:   "beta",
+			StakingAddress: testAddress(4).String(),
+			Refund:         "0",
+			Rewards:        []*mockanalytics.Reward{{Voter: testAddress(4).String(), Amount: "400"}},
+		},
+	)
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	c.SetChainMeta(1000, 60)
+	cfg.Distribution.ChunkSize = 2
+	hermes := newFakeHermes(cfg, c)
+	ctx := context.Background()
+
+	// the first window has a persisted plan, the second is distributed to beta without one
+	require.NoError(Reward(ctx, cfg, c, nil))
+	require.NoError(sendRewards(ctx, cfg, c, "beta", big.NewInt(48), hermes.minTips,
+		[]common.Address{common.BytesToAddress(testAddress(4).Bytes())}, []*big.Int{big.NewInt(450)}))
+	require.NoError(commitDistributions(ctx, cfg, c, big.NewInt(48), [][32]byte{stringToBytes32("beta")}))
+
+	h, err := GetHistory(ctx, cfg, c, &HistoryFilter{})
+	require.NoError(err)
+	require.Equal([]*WindowHistory{
+		{EndEpoch: 24, Delegates: []*DelegateHistory{
+			{DelegateName: "alpha", Count: 3, Amount: "600"},
+			{DelegateName: "beta", Count: 1, Amount: "400"},
+		}},
+		{EndEpoch: 48, Delegates: []*DelegateHistory{{DelegateName: "beta", Count: 1, Amount: "450"}}},
+	}, h.Windows)
+
+	h, err = GetHistory(ctx, cfg, c, &HistoryFilter{Delegates: []string{"alpha"}})
+	require.NoError(err)
+	require.Len(h.Windows, 1)
+	require.Equal(uint64(24), h.Windows[0].EndEpoch)
+
+	h, err = GetHistory(ctx, cfg, c, &HistoryFilter{FromEpoch: 25})
+	require.NoError(err)
+	require.Len(h.Windows, 1)
+	require.Equal(uint64(48), h.Windows[0].EndEpoch)
+
+	h, err = GetHistory(ctx, cfg, c, &HistoryFilter{ToEpoch: 47})
+	require.NoError(err)
+	require.Len(h.Windows, 1)
+	require.Equal(uint64(24), h.Windows[0].EndEpoch)
+
+	var out bytes.Buffer
+	require.NoError(h.WriteCSV(&out))
+	require.Equal("endEpoch,delegateName,count,amount\n24,alpha,3,600\n24,beta,1,400\n", out.String())
+}
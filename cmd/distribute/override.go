@@ -0,0 +1,203 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/spf13/cobra"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// OverrideCmd is the override command
+var OverrideCmd = &cobra.Command{
+	Use:   "override",
+	Short: "Manage the addresses paid in place of voter or staking addresses",
+}
+
+var overrideAddCmd = &cobra.Command{
+	Use:   "add ADDRESS REPLACEMENT",
+	Short: "Pay REPLACEMENT in place of ADDRESS in the windows ending in the epoch range",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := connectOverrideDatabase(); err != nil {
+			return err
+		}
+		override := &dao.AddressOverride{
+			Address:     args[0],
+			Replacement: args[1],
+			FromEpoch:   overrideFromEpoch,
+			ToEpoch:     overrideToEpoch,
+			Reason:      overrideReason,
+		}
+		if err := AddAddressOverride(override); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "added address override %d\n", override.ID)
+		return nil
+	},
+}
+
+var overrideListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the address overrides",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if err := connectOverrideDatabase(); err != nil {
+			return err
+		}
+		overrides, err := dao.FindAddressOverrides(overrideAll)
+		if err != nil {
+			return err
+		}
+		switch overrideOutput {
+		case "json":
+			return WriteAddressOverridesJSON(cmd.OutOrStdout(), overrides)
+		case "table":
+			return WriteAddressOverridesTable(cmd.OutOrStdout(), overrides)
+		default:
+			return fmt.Errorf("unknown output format %s", overrideOutput)
+		}
+	},
+}
+
+var overrideRemoveCmd = &cobra.Command{
+	Use:   "remove ID",
+	Short: "Remove the address override of ID, keeping it for audit",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid address override id %s", args[0])
+		}
+		if err := connectOverrideDatabase(); err != nil {
+			return err
+		}
+		return dao.DeleteAddressOverride(uint(id))
+	},
+}
+
+var (
+	overrideFromEpoch uint64
+	overrideToEpoch   uint64
+	overrideReason    string
+	overrideAll       bool
+	overrideOutput    string
+)
+
+func init() {
+	overrideAddCmd.Flags().Uint64Var(&overrideFromEpoch, "from-epoch", 0, "first end epoch of the windows to override")
+	overrideAddCmd.Flags().Uint64Var(&overrideToEpoch, "to-epoch", 0, "last end epoch of the windows to override, no end if 0")
+	overrideAddCmd.Flags().StringVar(&overrideReason, "reason", "", "reason of the override")
+	overrideAddCmd.MarkFlagRequired("reason")
+	overrideListCmd.Flags().BoolVar(&overrideAll, "all", false, "list the removed overrides too")
+	overrideListCmd.Flags().StringVarP(&overrideOutput, "output", "o", "table", "output format, table or json")
+	OverrideCmd.AddCommand(overrideAddCmd, overrideListCmd, overrideRemoveCmd)
+}
+
+func connectOverrideDatabase() error {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		return err
+	}
+	return dao.ConnectDatabase(&cfg.Database)
+}
+
+// AddAddressOverride saves override, if its addresses are valid and no other override of either address applies to
+// the same windows, so an address is never replaced twice or by a replaced address
+func AddAddressOverride(override *dao.AddressOverride) error {
+	for _, addr := range []string{override.Address, override.Replacement} {
+		if _, err := address.FromString(addr); err != nil {
+			return fmt.Errorf("invalid address %s: %v", addr, err)
+		}
+	}
+	if override.Address == override.Replacement {
+		return fmt.Errorf("address %s replaced by itself", override.Address)
+	}
+	if override.ToEpoch != 0 && override.ToEpoch < override.FromEpoch {
+		return fmt.Errorf("to epoch %d before from epoch %d", override.ToEpoch, override.FromEpoch)
+	}
+	existing, err := dao.FindAddressOverrides(false)
+	if err != nil {
+		return err
+	}
+	for _, o := range existing {
+		if !overlaps(&o, override) {
+			continue
+		}
+		switch {
+		case o.Address == override.Address:
+			return fmt.Errorf("address %s already replaced by override %d", override.Address, o.ID)
+		case o.Address == override.Replacement:
+			return fmt.Errorf("replacement %s is replaced by override %d", override.Replacement, o.ID)
+		case o.Replacement == override.Address:
+			return fmt.Errorf("address %s is the replacement of override %d", override.Address, o.ID)
+		}
+	}
+	return override.Save(nil)
+}
+
+// overlaps returns whether two overrides apply to a same window
+func overlaps(a, b *dao.AddressOverride) bool {
+	toEpoch := func(o *dao.AddressOverride) uint64 {
+		if o.ToEpoch == 0 {
+			return math.MaxUint64
+		}
+		return o.ToEpoch
+	}
+	return a.FromEpoch <= toEpoch(b) && b.FromEpoch <= toEpoch(a)
+}
+
+// addressOverridesOf returns the replacement of every address overridden in the window ending at endEpoch
+func addressOverridesOf(endEpoch uint64) (map[string]string, error) {
+	overrides, err := dao.FindAddressOverridesByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	replacements := make(map[string]string, len(overrides))
+	for _, o := range overrides {
+		replacements[o.Address] = o.Replacement
+	}
+	return replacements, nil
+}
+
+// WriteAddressOverridesJSON writes the overrides as JSON
+func WriteAddressOverridesJSON(w io.Writer, overrides []dao.AddressOverride) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(overrides)
+}
+
+// WriteAddressOverridesTable writes the overrides as a table
+func WriteAddressOverridesTable(w io.Writer, overrides []dao.AddressOverride) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tADDRESS\tREPLACEMENT\tFROM EPOCH\tTO EPOCH\tCREATED\tREMOVED\tREASON")
+	for _, o := range overrides {
+		toEpoch, removed := "-", "-"
+		if o.ToEpoch != 0 {
+			toEpoch = strconv.FormatUint(o.ToEpoch, 10)
+		}
+		if o.DeletedAt != nil {
+			removed = o.DeletedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", o.ID, o.Address, o.Replacement, o.FromEpoch, toEpoch,
+			o.CreatedAt.Format(time.RFC3339), removed, o.Reason)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestAddressOverride(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	// an address is replaced once per window, and never by a replaced address
+	override := func(addr, replacement byte, fromEpoch, toEpoch uint64) *dao.AddressOverride {
+		return &dao.AddressOverride{Address: testAddress(addr).String(), Replacement: testAddress(replacement).String(),
+			FromEpoch: fromEpoch, ToEpoch: toEpoch, Reason: "test"}
+	}
+	require.NoError(AddAddressOverride(override(2, 5, 0, 0)))
+	require.NoError(AddAddressOverride(override(3, 6, 0, 24)))
+	require.NoError(AddAddressOverride(override(3, 7, 25, 0)))
+	for _, o := range []*dao.AddressOverride{
+		{Address: "io1invalid", Replacement: testAddress(5).String()},
+		override(4, 4, 0, 0),
+		override(4, 5, 48, 24),
+		override(2, 8, 100, 0),
+		override(4, 2, 0, 0),
+		override(5, 8, 0, 0),
+	} {
+		require.Error(AddAddressOverride(o), o.Address)
+	}
+	overrides, err := addressOverridesOf(24)
+	require.NoError(err)
+	require.Equal(testAddress(5).String(), overrides[testAddress(2).String()])
+	require.Equal(testAddress(6).String(), overrides[testAddress(3).String()])
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(3).String(),
+		Refund:         "1000",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100000"},
+			{Voter: testAddress(2).String(), Amount: "200000"},
+			{Voter: testAddress(5).String(), Amount: "300000"},
+		},
+	})
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	hermes := newFakeHermes(cfg, c)
+	hermes.buckets[common.BytesToAddress(testAddress(5).Bytes())] = 7
+
+	// the replacements are paid in place of the voter and the staking address, the auto deposit of a replacement
+	// included
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+	plan, err := dao.FindPlanByEndEpoch(24)
+	require.NoError(err)
+	require.Contains(plan.Content, common.BytesToAddress(testAddress(5).Bytes()).Hex())
+	require.Contains(plan.Content, common.BytesToAddress(testAddress(6).Bytes()).Hex())
+	require.NotContains(plan.Content, common.BytesToAddress(testAddress(2).Bytes()).Hex())
+	require.NotContains(plan.Content, common.BytesToAddress(testAddress(3).Bytes()).Hex())
+	require.Equal("1000", hermes.received[common.BytesToAddress(testAddress(6).Bytes())].String())
+	records, err := dao.FindDropRecordsByEndEpoch(24)
+	require.NoError(err)
+	require.Len(records, 1)
+	require.Equal(testAddress(5).String(), records[0].Voter)
+	require.Equal("500000", records[0].Amount)
+
+	// a removed override no longer applies, and is still listed for audit
+	all, err := dao.FindAddressOverrides(false)
+	require.NoError(err)
+	require.Len(all, 4)
+	require.NoError(dao.DeleteAddressOverride(all[1].ID))
+	require.Error(dao.DeleteAddressOverride(all[1].ID))
+	overrides, err = addressOverridesOf(24)
+	require.NoError(err)
+	require.Len(overrides, 2)
+	all, err = dao.FindAddressOverrides(true)
+	require.NoError(err)
+	require.Len(all, 4)
+	require.NotNil(all[1].DeletedAt)
+	var table bytes.Buffer
+	require.NoError(WriteAddressOverridesTable(&table, all))
+	require.True(strings.HasPrefix(table.String(), "ID  ADDRESS"))
+}
+
+func TestSeededAddressOverride(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	// the remap of earlier releases is seeded once, and a removed seed is not added again
+	overrides, err := addressOverridesOf(24)
+	require.NoError(err)
+	require.Equal(map[string]string{
+		"io16y9wk2xnwurvtgmd2mds2gcdfe2lmzad6dcw29": "io16dkdajys8609qxf78wmmzssgfgvqkk0funzp0r",
+	}, overrides)
+	all, err := dao.FindAddressOverrides(false)
+	require.NoError(err)
+	require.Len(all, 1)
+	require.NoError(dao.DeleteAddressOverride(all[0].ID))
+	require.NoError(dao.SetDatabase(dao.DB(), nil, nil))
+	overrides, err = addressOverridesOf(24)
+	require.NoError(err)
+	require.Empty(overrides)
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+// ErrPlanChanged indicates the plan recomputed from the bookkeeping differs from the persisted plan of the window
+var ErrPlanChanged = errors.New("distribution plan changed")
+
+// Plan is the distribution of a window as persisted on its first fetch. A resumed distribution follows the persisted
+// plan, so the recipients already paid on chain are the ones the contract counts.
+type Plan struct {
+	EndEpoch  uint64          `json:"endEpoch"`
+	Delegates []*DelegatePlan `json:"delegates"`
+}
+
+// DelegatePlan is the sorted recipients and amounts of a delegate, and the end indexes of its chunks
+type DelegatePlan struct {
+	DelegateName string   `json:"delegateName"`
+	StartEpoch   uint64   `json:"startEpoch"`
+	ServiceFee   string   `json:"serviceFee"`
+	FeePolicy    string   `json:"feePolicy,omitempty"`
+	Refund       string   `json:"refund"`
+	Recipients   []string `json:"recipients"`
+	Amounts      []string `json:"amounts"`
+	ChunkEnds    []int    `json:"chunkEnds"`
+}
+
+// newPlan returns the plan of distributions, cut in chunks of chunkSize
+func newPlan(endEpoch uint64, distributions []*DistributionInfo, chunkSize int) *Plan {
+	p := &Plan{EndEpoch: endEpoch}
+	for _, dist := range distributions {
+		d := &DelegatePlan{
+			DelegateName: dist.DelegateName,
+			StartEpoch:   dist.StartEpoch,
+			ServiceFee:   dist.ServiceFee.String(),
+			FeePolicy:    dist.FeePolicy,
+			Refund:       dist.Refund.String(),
+		}
+		for i, recipient := range dist.RecipientList {
+			d.Recipients = append(d.Recipients, recipient.Hex())
+			d.Amounts = append(d.Amounts, dist.AmountList[i].String())
+		}
+		for end := chunkSize; end < len(d.Recipients); end += chunkSize {
+			d.ChunkEnds = append(d.ChunkEnds, end)
+		}
+		if len(d.Recipients) > 0 {
+			d.ChunkEnds = append(d.ChunkEnds, len(d.Recipients))
+		}
+		p.Delegates = append(p.Delegates, d)
+	}
+	return p
+}
+
+// Hash returns the hex encoded hash of the recipients and amounts of the plan, which the chunks and the names of the
+// fee policies do not change
+func (p *Plan) Hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", p.EndEpoch)
+	for _, d := range p.Delegates {
+		fmt.Fprintf(h, "%s %d %s %s %d\n", d.DelegateName, d.StartEpoch, d.ServiceFee, d.Refund, len(d.Recipients))
+		for i, recipient := range d.Recipients {
+			fmt.Fprintf(h, "%s %s\n", recipient, d.Amounts[i])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// nextChunkEnd returns the end index of the chunk starting at distributedCount
+func (d *DelegatePlan) nextChunkEnd(distributedCount int) int {
+	for _, end := range d.ChunkEnds {
+		if end > distributedCount {
+			return end
+		}
+	}
+	return len(d.Recipients)
+}
+
+// distributions returns the distributions of the plan
+func (p *Plan) distributions() ([]*DistributionInfo, error) {
+	distributions := make([]*DistributionInfo, 0, len(p.Delegates))
+	for _, d := range p.Delegates {
+		if len(d.Recipients) != len(d.Amounts) {
+			return nil, errors.New("length does not match")
+		}
+		serviceFee, ok := new(big.Int).SetString(d.ServiceFee, 10)
+		if !ok {
+			return nil, errors.New("failed to convert string to big int")
+		}
+		refund, ok := new(big.Int).SetString(d.Refund, 10)
+		if !ok {
+			return nil, errors.New("failed to convert string to big int")
+		}
+		dist := &DistributionInfo{
+			DelegateName:  d.DelegateName,
+			StartEpoch:    d.StartEpoch,
+			RecipientList: make([]common.Address, 0, len(d.Recipients)),
+			AmountList:    make([]*big.Int, 0, len(d.Amounts)),
+			ServiceFee:    serviceFee,
+			FeePolicy:     d.FeePolicy,
+			Refund:        refund,
+		}
+		for i, recipient := range d.Recipients {
+			amount, ok := new(big.Int).SetString(d.Amounts[i], 10)
+			if !ok {
+				return nil, errors.New("failed to convert string to big int")
+			}
+			dist.RecipientList = append(dist.RecipientList, common.HexToAddress(recipient))
+			dist.AmountList = append(dist.AmountList, amount)
+		}
+		distributions = append(distributions, dist)
+	}
+	return distributions, nil
+}
+
+// loadPlan persists the plan of window if it is fetched for the first time, and returns the persisted plan. It
+// returns an error caused by ErrPlanChanged, and alerts it, if the plan recomputed from window differs. A dry run
+// returns the plan to be persisted without saving it, and does not alert.
+func loadPlan(ctx context.Context, cfg *config.Config, c chain.Client, window *distributionWindow, dryRun bool) (*Plan, error) {
+	chunkSize, err := maxChunkSize(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	plan := newPlan(window.endEpoch.Uint64(), window.distributions, chunkSize)
+	hash := plan.Hash()
+
+	stored, err := dao.FindPlanByEndEpoch(plan.EndEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		if dryRun {
+			return plan, nil
+		}
+		content, err := json.Marshal(plan)
+		if err != nil {
+			return nil, err
+		}
+		if err := (&dao.Plan{EndEpoch: plan.EndEpoch, Hash: hash, Content: string(content)}).Save(nil); err != nil {
+			return nil, errors.Wrap(err, "failed to save distribution plan")
+		}
+		logger.Ctx(ctx).Info("saved distribution plan", zap.String("planHash", hash))
+		return plan, nil
+	}
+
+	persisted := &Plan{}
+	if err := json.Unmarshal([]byte(stored.Content), persisted); err != nil {
+		return nil, errors.Wrap(err, "failed to parse distribution plan")
+	}
+	if persisted.Hash() != stored.Hash {
+		return nil, errors.Errorf("persisted distribution plan of end epoch %d does not match its hash %s", plan.EndEpoch, stored.Hash)
+	}
+	if hash != stored.Hash {
+		err := errors.Wrapf(ErrPlanChanged, "end epoch %d, persisted %s, recomputed %s", plan.EndEpoch, stored.Hash, hash)
+		if dryRun {
+			return nil, err
+		}
+		notify.Send(ctx, &notify.Event{
+			Type:     notify.EventPlanChanged,
+			Message:  err.Error(),
+			EndEpoch: plan.EndEpoch,
+			Fields:   map[string]string{"persisted": stored.Hash, "recomputed": hash},
+		})
+		return nil, err
+	}
+	return persisted, nil
+}
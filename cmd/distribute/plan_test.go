@@ -0,0 +1,81 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+func TestPlan(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	alpha := &mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100"},
+			{Voter: testAddress(2).String(), Amount: "200"},
+			{Voter: testAddress(3).String(), Amount: "300"},
+		},
+	}
+	analytics := newAnalyticsServer(alpha, &mockanalytics.Delegate{
+		DelegateName:   "beta",
+		StakingAddress: testAddress(4).String(),
+		Refund:         "0",
+		Rewards:        []*mockanalytics.Reward{{Voter: testAddress(4).String(), Amount: "400"}},
+	})
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	c.SetChainMeta(100, 30)
+	cfg.Distribution.ChunkSize = 2
+	hermes := newFakeHermes(cfg, c)
+
+	sink := notify.NewSink()
+	alerts := httptest.NewServer(sink)
+	defer alerts.Close()
+	notify.SetNotifiers(notify.NewWebhook(alerts.URL))
+	defer notify.SetNotifiers()
+
+	// the plan is persisted on the first fetch
+	cycle := &dao.Cycle{StartEpoch: 1, EndEpoch: 24, Status: dao.CycleClaimed}
+	require.NoError(cycle.Save(nil))
+	hermes.revert = "beta"
+	require.Error(Reward(context.Background(), cfg, c, cycle))
+	require.Equal(1, cycle.DistributedDelegates)
+	stored, err := dao.FindPlanByEndEpoch(24)
+	require.NoError(err)
+	require.NotNil(stored)
+	require.Contains(stored.Content, `"chunkEnds":[2,3]`)
+	hash := stored.Hash
+
+	// a shifted bookkeeping is refused
+	alpha.Rewards[1].Amount = "250"
+	err = Reward(context.Background(), cfg, c, cycle)
+	require.Equal(ErrPlanChanged, errors.Cause(err))
+	require.Equal([]string{notify.EventDistributeFailed, notify.EventPlanChanged}, sink.Types())
+
+	// a changed chunk size does not change the plan
+	alpha.Rewards[1].Amount = "200"
+	cfg.Distribution.ChunkSize = 1
+	require.NoError(Reward(context.Background(), cfg, c, cycle))
+	require.Equal(dao.CycleCommitted, cycle.Status)
+	stored, err = dao.FindPlanByEndEpoch(24)
+	require.NoError(err)
+	require.Equal(hash, stored.Hash)
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+// Requirement is the balance the vault needs to finish the distribution of a window: the rewards, tips and gas of the
+// chunks not distributed yet, the gas of the commit, the amounts of the pending auto deposits, and the service fees
+// swept to the treasury with the gas of the sweep. The gas of every action is bounded by the configured or maximum
+// limit at the current price.
+type Requirement struct {
+	Balance         *big.Int
+	Delegates       []*DelegateRequirement
+	CommitGas       *big.Int
+	PendingDeposits *big.Int
+	FeeSweep        *big.Int
+	FeeSweepGas     *big.Int
+	Total           *big.Int
+}
+
+// DelegateRequirement is the balance needed by the remaining chunks of a delegate
+type DelegateRequirement struct {
+	DelegateName string
+	Chunks       int
+	Amount       *big.Int
+	Tips         *big.Int
+	Gas          *big.Int
+}
+
+// InsufficientBalanceError is returned if the vault balance cannot cover the requirement of a distribution
+type InsufficientBalanceError struct {
+	Requirement *Requirement
+}
+
+// Shortfall returns the amount the balance lacks, 0 if it covers the requirement
+func (r *Requirement) Shortfall() *big.Int {
+	shortfall := new(big.Int).Sub(r.Total, r.Balance)
+	if shortfall.Sign() < 0 {
+		return big.NewInt(0)
+	}
+	return shortfall
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	r := e.Requirement
+	var b strings.Builder
+	fmt.Fprintf(&b, "insufficient vault balance %s, required %s, shortfall %s\n", r.Balance, r.Total, r.Shortfall())
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DELEGATE\tCHUNKS\tAMOUNT\tTIPS\tGAS")
+	for _, d := range r.Delegates {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", d.DelegateName, d.Chunks, d.Amount, d.Tips, d.Gas)
+	}
+	fmt.Fprintf(tw, "commit\t\t\t\t%s\n", r.CommitGas)
+	fmt.Fprintf(tw, "pending deposits\t\t%s\t\t\n", r.PendingDeposits)
+	fmt.Fprintf(tw, "fee sweep\t\t%s\t\t%s\n", r.FeeSweep, r.FeeSweepGas)
+	tw.Flush()
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// requirementOf returns the requirement of the distribution of window in the chunks of plan, skipping the first
+// distributedDelegates delegates and the recipients already distributed on chain
+func requirementOf(ctx context.Context, cfg *config.Config, c chain.Client, window *distributionWindow, plan *Plan, distributedDelegates int) (*Requirement, error) {
+	gas, err := chain.NewGasEstimator(cfg.Gas).UpperBound(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	gasPerAction := gas.Fee()
+
+	r := &Requirement{
+		CommitGas: new(big.Int).Set(gasPerAction),
+		Total:     new(big.Int).Set(gasPerAction),
+	}
+	for i, dist := range window.distributions {
+		if i < distributedDelegates {
+			continue
+		}
+		distributedCount, err := getDistributedCount(ctx, cfg, c, dist.DelegateName)
+		if err != nil {
+			return nil, err
+		}
+		remaining := len(dist.AmountList) - int(distributedCount)
+		if remaining <= 0 {
+			continue
+		}
+		chunks := 0
+		for end := int(distributedCount); end < len(dist.AmountList); chunks++ {
+			end = plan.Delegates[i].nextChunkEnd(end)
+		}
+		d := &DelegateRequirement{
+			DelegateName: dist.DelegateName,
+			Chunks:       chunks,
+			Amount:       big.NewInt(0),
+			Tips:         new(big.Int).Mul(window.minTips, big.NewInt(int64(chunks))),
+			Gas:          new(big.Int).Mul(gasPerAction, big.NewInt(int64(chunks))),
+		}
+		for _, amount := range dist.AmountList[distributedCount:] {
+			d.Amount.Add(d.Amount, amount)
+		}
+		r.Total.Add(r.Total, d.Amount).Add(r.Total, d.Tips).Add(r.Total, d.Gas)
+		r.Delegates = append(r.Delegates, d)
+	}
+
+	pending, err := dao.SumNewDropRecordAmounts()
+	if err != nil {
+		return nil, err
+	}
+	r.PendingDeposits = pending
+	r.Total.Add(r.Total, pending)
+
+	r.FeeSweep, r.FeeSweepGas = big.NewInt(0), big.NewInt(0)
+	sweep, err := dao.FindFeeSweepByEndEpoch(window.endEpoch.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Distribution.FeeTreasury != "" && (sweep == nil || sweep.Status != dao.FeeSweepCompleted) {
+		for _, dist := range window.distributions {
+			r.FeeSweep.Add(r.FeeSweep, dist.ServiceFee)
+		}
+		if r.FeeSweep.Sign() > 0 {
+			r.FeeSweepGas.Set(gasPerAction)
+		}
+		r.Total.Add(r.Total, r.FeeSweep).Add(r.Total, r.FeeSweepGas)
+	}
+
+	balance, err := c.GetBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.Balance = balance
+	return r, nil
+}
+
+// preflight returns an InsufficientBalanceError, and alerts it, if the vault cannot cover the remaining distribution
+// of window in the chunks of plan
+func preflight(ctx context.Context, cfg *config.Config, c chain.Client, window *distributionWindow, plan *Plan, distributedDelegates int) error {
+	r, err := requirementOf(ctx, cfg, c, window, plan, distributedDelegates)
+	if err != nil {
+		return err
+	}
+	if r.Shortfall().Sign() == 0 {
+		return nil
+	}
+	err = &InsufficientBalanceError{Requirement: r}
+	notify.Send(ctx, &notify.Event{
+		Type:     notify.EventLowBalance,
+		Message:  fmt.Sprintf("vault balance %s cannot cover the distribution of %s", r.Balance, r.Total),
+		EndEpoch: window.endEpoch.Uint64(),
+		Fields:   map[string]string{"shortfall": r.Shortfall().String()},
+	})
+	return err
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestPreflight(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "30",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100"},
+			{Voter: testAddress(2).String(), Amount: "200"},
+			{Voter: testAddress(3).String(), Amount: "300"},
+		},
+	})
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	c.SetChainMeta(100, 30)
+	cfg.Distribution.ChunkSize = 2
+	cfg.Distribution.WaiverThreshold = 100
+	cfg.Distribution.BaseCharge = config.NewBigInt(big.NewInt(30))
+	cfg.Distribution.FeeTreasury = testAddress(20).String()
+	hermes := newFakeHermes(cfg, c)
+	require.NoError(dao.DropRecord{DelegateName: "alpha", Voter: testAddress(4).String(), Amount: "50", Status: "new"}.Save(nil))
+
+	// rewards 600, tips 2 * 5 and gas 2 * 100000 of the chunks, gas 100000 of the commit, the pending deposit 50, and
+	// the service fee 30 with gas 100000 of its sweep
+	c.SetBalance(big.NewInt(400600))
+	err := Reward(context.Background(), cfg, c, nil)
+	require.Error(err)
+	shortfall, ok := err.(*InsufficientBalanceError)
+	require.True(ok)
+	require.Equal("400690", shortfall.Requirement.Total.String())
+	require.Equal("90", shortfall.Requirement.Shortfall().String())
+	require.Len(shortfall.Requirement.Delegates, 1)
+	require.Equal(2, shortfall.Requirement.Delegates[0].Chunks)
+	require.Equal("30", shortfall.Requirement.FeeSweep.String())
+	require.Contains(err.Error(), "insufficient vault balance 400600, required 400690, shortfall 90")
+	require.Empty(c.Actions())
+
+	// the remaining chunks follow the plan, so a planned chunk distributed in part leaves two chunks
+	hermes.distributedCount[stringToBytes32("alpha")] = 1
+	c.SetBalance(big.NewInt(0))
+	err = Reward(context.Background(), cfg, c, nil)
+	require.Error(err)
+	shortfall, ok = err.(*InsufficientBalanceError)
+	require.True(ok)
+	require.Equal(2, shortfall.Requirement.Delegates[0].Chunks)
+	hermes.distributedCount[stringToBytes32("alpha")] = 0
+
+	c.SetBalance(big.NewInt(400690))
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+}
@@ -0,0 +1,584 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/iotexproject/iotex-proto/golang/iotexapi"
+	"github.com/iotexproject/iotex-proto/golang/iotextypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// Kinds of reconciliation findings
+const (
+	// FindingMissing is a planned payment not found on chain, or an auto deposit which failed
+	FindingMissing = "missing"
+	// FindingDuplicated is a payment on chain beyond the plan, or to a recipient paid already
+	FindingDuplicated = "duplicated"
+	// FindingMismatched is a payment or an auto deposit whose amount differs from the plan
+	FindingMismatched = "mismatched"
+	// FindingRedirected is a payment of the planned amount to the forward address the recipient registers
+	FindingRedirected = "redirected"
+	// FindingMisdirected is a payment of the planned amount to neither the recipient nor its forward address
+	FindingMisdirected = "misdirected"
+	// FindingPending is an auto deposit which is not sent yet
+	FindingPending = "pending"
+)
+
+// reconcileBlocks is the number of blocks of the logs queried at a time
+const reconcileBlocks = 1000
+
+// ReconcileCmd is the reconcile command
+var ReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Compare the payments and the fee sweep of a window on chain against its plan and records",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+
+		r, err := Reconcile(context.Background(), cfg, c, reconcileEndEpoch)
+		if err != nil {
+			return err
+		}
+		switch reconcileOutput {
+		case "json":
+			err = r.WriteJSON(cmd.OutOrStdout())
+		case "table":
+			err = r.WriteTable(cmd.OutOrStdout())
+		default:
+			err = fmt.Errorf("unknown output format %s", reconcileOutput)
+		}
+		if err != nil {
+			return err
+		}
+		if problems := r.Problems(); problems > 0 {
+			return fmt.Errorf("%d problems found in the window ending at epoch %d", problems, r.EndEpoch)
+		}
+		return nil
+	},
+}
+
+var (
+	reconcileEndEpoch uint64
+	reconcileOutput   string
+)
+
+func init() {
+	ReconcileCmd.Flags().Uint64Var(&reconcileEndEpoch, "end-epoch", 0, "end epoch of the window")
+	ReconcileCmd.Flags().StringVarP(&reconcileOutput, "output", "o", "table", "output format, table or json")
+	ReconcileCmd.MarkFlagRequired("end-epoch")
+}
+
+// Reconciliation is the diff of the payments of a window found on chain against its plan and drop records
+type Reconciliation struct {
+	EndEpoch  uint64                    `json:"endEpoch"`
+	PlanHash  string                    `json:"planHash"`
+	Committed bool                      `json:"committed"`
+	Delegates []*DelegateReconciliation `json:"delegates"`
+	FeeSweep  *FeeSweepReconciliation   `json:"feeSweep,omitempty"`
+	Findings  []*ReconciliationFinding  `json:"findings"`
+}
+
+// FeeSweepReconciliation is the sweep of the service fees of the window to the treasury
+type FeeSweepReconciliation struct {
+	Treasury   string `json:"treasury"`
+	Fees       string `json:"fees"`
+	Amount     string `json:"amount"`
+	Status     string `json:"status"`
+	ActionHash string `json:"actionHash,omitempty"`
+}
+
+// DelegateReconciliation is the summary of the payments of a delegate
+type DelegateReconciliation struct {
+	DelegateName string `json:"delegateName"`
+	Planned      int    `json:"planned"`
+	Paid         int    `json:"paid"`
+	Deposits     int    `json:"deposits"`
+	Actions      int    `json:"actions"`
+}
+
+// ReconciliationFinding is a payment which does not match the plan
+type ReconciliationFinding struct {
+	Kind         string `json:"kind"`
+	DelegateName string `json:"delegateName"`
+	Recipient    string `json:"recipient"`
+	Expected     string `json:"expected"`
+	Actual       string `json:"actual"`
+	ActionHash   string `json:"actionHash,omitempty"`
+}
+
+// payment is a Multisend transfer of a distributeRewards action
+type payment struct {
+	to         common.Address
+	amount     *big.Int
+	actionHash string
+}
+
+// unpaidRecipient is a planned recipient whose payment is not found at its address or forward address
+type unpaidRecipient struct {
+	addr     address.Address
+	target   common.Address
+	expected *big.Int
+}
+
+// paymentMatcher matches the payments of a delegate to its planned recipients by destination, as a re-sent or missing
+// chunk shifts their order, and a recipient not paid at its address must be paid at the forward address it registers
+type paymentMatcher struct {
+	cfg       *config.Config
+	c         chain.Client
+	endEpoch  uint64
+	fr        *ForwardRegistration
+	unmatched map[common.Address][]*payment
+}
+
+func newPaymentMatcher(cfg *config.Config, c chain.Client, endEpoch uint64) *paymentMatcher {
+	return &paymentMatcher{cfg: cfg, c: c, endEpoch: endEpoch}
+}
+
+// reset starts matching the payments paid of another delegate
+func (m *paymentMatcher) reset(paid []*payment) {
+	m.unmatched = make(map[common.Address][]*payment)
+	for _, p := range paid {
+		m.unmatched[p.to] = append(m.unmatched[p.to], p)
+	}
+}
+
+// target returns the address the payment of addr is expected at, addr itself if a payment is left there, or else the
+// forward address it registers at the end of the window
+func (m *paymentMatcher) target(ctx context.Context, addr common.Address) (common.Address, error) {
+	if len(m.unmatched[addr]) > 0 {
+		return addr, nil
+	}
+	if m.fr == nil {
+		fr, err := NewForwardRegistration(ctx, m.cfg, m.c)
+		if err != nil {
+			return common.Address{}, err
+		}
+		m.fr = fr
+	}
+	return m.fr.ForwardAddress(ctx, addr, m.endEpoch)
+}
+
+// take removes and returns the first payment left to to of amount, or of any amount if amount is nil
+func (m *paymentMatcher) take(to common.Address, amount *big.Int) *payment {
+	for i, p := range m.unmatched[to] {
+		if amount == nil || p.amount.Cmp(amount) == 0 {
+			m.unmatched[to] = append(m.unmatched[to][:i:i], m.unmatched[to][i+1:]...)
+			return p
+		}
+	}
+	return nil
+}
+
+// left returns the payments of paid not taken, in order
+func (m *paymentMatcher) left(paid []*payment) []*payment {
+	var left []*payment
+	for _, p := range paid {
+		if len(m.unmatched[p.to]) > 0 && m.unmatched[p.to][0] == p {
+			m.unmatched[p.to] = m.unmatched[p.to][1:]
+			left = append(left, p)
+		}
+	}
+	return left
+}
+
+// Problems returns the number of the missing, duplicated, mismatched and misdirected payments
+func (r *Reconciliation) Problems() int {
+	problems := 0
+	for _, f := range r.Findings {
+		switch f.Kind {
+		case FindingMissing, FindingDuplicated, FindingMismatched, FindingMisdirected:
+			problems++
+		}
+	}
+	return problems
+}
+
+// Reconcile scans the Distribute and CommitDistributions events of the hermes contract and the Transfer events of the
+// Multisend contract from the end of the window ending at endEpoch until its commit, and diffs the payments of every
+// delegate, by destination, against its persisted plan, drop records and forward addresses, and the sweep of the
+// service fees of the window against the recorded fees
+func Reconcile(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) (*Reconciliation, error) {
+	stored, err := dao.FindPlanByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("no distribution plan of end epoch %d", endEpoch)
+	}
+	plan := &Plan{}
+	if err := json.Unmarshal([]byte(stored.Content), plan); err != nil {
+		return nil, errors.Wrap(err, "failed to parse distribution plan")
+	}
+	records, err := dao.FindDropRecordsByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	payments, actions, committed, err := scanPayments(ctx, cfg, c, endEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reconciliation{EndEpoch: endEpoch, PlanHash: stored.Hash, Committed: committed}
+	add := func(kind, delegateName, recipient string, expected, actual interface{}, actionHash string) {
+		r.Findings = append(r.Findings, &ReconciliationFinding{
+			Kind:         kind,
+			DelegateName: delegateName,
+			Recipient:    recipient,
+			Expected:     fmt.Sprint(expected),
+			Actual:       fmt.Sprint(actual),
+			ActionHash:   actionHash,
+		})
+	}
+	deposits := make(map[string]map[string]*dao.DropRecord)
+	for i := range records {
+		record := &records[i]
+		if _, ok := deposits[record.DelegateName]; !ok {
+			deposits[record.DelegateName] = make(map[string]*dao.DropRecord)
+		}
+		deposits[record.DelegateName][record.Voter] = record
+	}
+
+	m := newPaymentMatcher(cfg, c, endEpoch)
+	for _, d := range plan.Delegates {
+		paid := payments[d.DelegateName]
+		delegate := &DelegateReconciliation{
+			DelegateName: d.DelegateName,
+			Planned:      len(d.Recipients),
+			Paid:         len(paid),
+			Actions:      actions[d.DelegateName],
+		}
+		r.Delegates = append(r.Delegates, delegate)
+		m.reset(paid)
+		destinations := make(map[common.Address]bool)
+		var unpaid []*unpaidRecipient
+		for i, recipient := range d.Recipients {
+			addr := common.HexToAddress(recipient)
+			ioAddr, err := address.FromBytes(addr.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			planned, ok := new(big.Int).SetString(d.Amounts[i], 10)
+			if !ok {
+				return nil, errors.New("failed to convert string to big int")
+			}
+			// an auto deposit voter is paid zero on chain and gets the amount from its drop record
+			expected := planned
+			if record, ok := deposits[d.DelegateName][ioAddr.String()]; ok {
+				delete(deposits[d.DelegateName], ioAddr.String())
+				delegate.Deposits++
+				expected = big.NewInt(0)
+				switch {
+				case record.Amount != planned.String():
+					add(FindingMismatched, d.DelegateName, ioAddr.String(), planned, record.Amount, record.Hash)
+				case record.Status == "new":
+					add(FindingPending, d.DelegateName, ioAddr.String(), planned, 0, "")
+				case record.Status != "completed":
+					add(FindingMissing, d.DelegateName, ioAddr.String(), planned, record.Status, record.Hash)
+				}
+			}
+			target, err := m.target(ctx, addr)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read forward address of %s", ioAddr.String())
+			}
+			destinations[target] = true
+			if p := m.take(target, expected); p != nil {
+				if target != addr {
+					to, err := address.FromBytes(p.to.Bytes())
+					if err != nil {
+						return nil, err
+					}
+					add(FindingRedirected, d.DelegateName, ioAddr.String(), ioAddr.String(), to.String(), p.actionHash)
+				}
+				continue
+			}
+			if p := m.take(target, nil); p != nil {
+				add(FindingMismatched, d.DelegateName, ioAddr.String(), expected, p.amount, p.actionHash)
+				continue
+			}
+			unpaid = append(unpaid, &unpaidRecipient{addr: ioAddr, target: target, expected: expected})
+		}
+		// a planned amount paid to no planned destination is misdirected, any other payment left is a duplicate
+		left := m.left(paid)
+		for _, u := range unpaid {
+			target, err := address.FromBytes(u.target.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			misdirected := -1
+			for i, p := range left {
+				if !destinations[p.to] && p.amount.Cmp(u.expected) == 0 {
+					misdirected = i
+					break
+				}
+			}
+			if misdirected < 0 {
+				add(FindingMissing, d.DelegateName, u.addr.String(), u.expected, "", "")
+				continue
+			}
+			p := left[misdirected]
+			left = append(left[:misdirected:misdirected], left[misdirected+1:]...)
+			to, err := address.FromBytes(p.to.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			add(FindingMisdirected, d.DelegateName, u.addr.String(), target.String(), to.String(), p.actionHash)
+		}
+		for _, p := range left {
+			to, err := address.FromBytes(p.to.Bytes())
+			if err != nil {
+				return nil, err
+			}
+			add(FindingDuplicated, d.DelegateName, to.String(), 0, p.amount, p.actionHash)
+		}
+		for voter, record := range deposits[d.DelegateName] {
+			add(FindingDuplicated, d.DelegateName, voter, 0, record.Amount, record.Hash)
+		}
+	}
+
+	// the service fees are swept to the treasury once the window is committed, if it is set
+	fees, err := serviceFeesOf(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	sweep, err := dao.FindFeeSweepByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if sweep == nil && (cfg.Distribution.FeeTreasury == "" || fees.Sign() == 0) {
+		return r, nil
+	}
+	r.FeeSweep = &FeeSweepReconciliation{Treasury: cfg.Distribution.FeeTreasury, Fees: fees.String()}
+	if sweep == nil {
+		add(FindingMissing, "", r.FeeSweep.Treasury, fees, "", "")
+		return r, nil
+	}
+	r.FeeSweep.Treasury, r.FeeSweep.Amount, r.FeeSweep.Status, r.FeeSweep.ActionHash = sweep.Treasury, sweep.Amount,
+		sweep.Status, sweep.Hash
+	switch sweep.Status {
+	case dao.FeeSweepCompleted:
+		h, err := hash.HexStringToHash256(sweep.Hash)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := c.GetReceipt(ctx, h)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return nil, err
+		}
+		if err != nil || receipt.Status != 1 {
+			add(FindingMissing, "", sweep.Treasury, fees, "not mined", sweep.Hash)
+		} else if sweep.Amount != fees.String() {
+			add(FindingMismatched, "", sweep.Treasury, fees, sweep.Amount, sweep.Hash)
+		}
+	case dao.FeeSweepNew, dao.FeeSweepSent:
+		add(FindingPending, "", sweep.Treasury, fees, sweep.Status, sweep.Hash)
+	default:
+		add(FindingMissing, "", sweep.Treasury, fees, sweep.Status, sweep.Hash)
+	}
+	return r, nil
+}
+
+// scanPayments returns the payments of every delegate in the window ending at endEpoch, in the order they are sent,
+// the number of distributeRewards actions of every delegate, and whether the window is committed
+func scanPayments(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) (map[string][]*payment, map[string]int, bool, error) {
+	hermesABI, err := abi.JSON(strings.NewReader(HermesABI))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	multisendABI, err := abi.JSON(strings.NewReader(MultisendABI))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	distributeID := hermesABI.Events["Distribute"].Id()
+	commitID := hermesABI.Events["CommitDistributions"].Id()
+	transferID := multisendABI.Events["Transfer"].Id()
+	filter := &iotexapi.LogsFilter{
+		Address: []string{cfg.Contracts.Hermes, cfg.Contracts.Multisend},
+		Topics:  []*iotexapi.Topics{{Topic: [][]byte{distributeID[:], commitID[:], transferID[:]}}},
+	}
+
+	payments := make(map[string][]*payment)
+	actions := make(map[string]int)
+	committed := false
+	// the transfers of an action are emitted before its Distribute event
+	var transfers []*payment
+	var actionHash []byte
+	err = scanWindowLogs(ctx, c, endEpoch, filter, func(log *iotextypes.Log) (bool, error) {
+		if !bytes.Equal(log.ActHash, actionHash) {
+			transfers, actionHash = nil, log.ActHash
+		}
+		switch {
+		case log.ContractAddress == cfg.Contracts.Multisend && bytes.Equal(log.Topics[0], transferID[:]):
+			p, err := transferOf(multisendABI, log)
+			if err != nil {
+				return false, err
+			}
+			transfers = append(transfers, p)
+		case log.ContractAddress == cfg.Contracts.Hermes && bytes.Equal(log.Topics[0], distributeID[:]):
+			var event struct {
+				StartEpoch      *big.Int
+				EndEpoch        *big.Int
+				NumOfRecipients *big.Int
+				TotalAmount     *big.Int
+			}
+			if err := hermesABI.Unpack(&event, "Distribute", log.Data); err != nil {
+				return false, err
+			}
+			if event.EndEpoch.Uint64() != endEpoch || len(log.Topics) < 2 {
+				return false, nil
+			}
+			delegateName := string(bytes.TrimRight(log.Topics[1], "\x00"))
+			payments[delegateName] = append(payments[delegateName], transfers...)
+			actions[delegateName]++
+		case log.ContractAddress == cfg.Contracts.Hermes && bytes.Equal(log.Topics[0], commitID[:]):
+			names, err := committedNamesOf(hermesABI, log, endEpoch)
+			if err != nil {
+				return false, err
+			}
+			if names != nil {
+				committed = true
+			}
+			return committed, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return payments, actions, committed, nil
+}
+
+// scanWindowLogs calls fn with the logs matching filter from the end of the window ending at endEpoch, in batches of
+// reconcileBlocks blocks, until the tip of the chain or the end of the batch in which fn returns true
+func scanWindowLogs(ctx context.Context, c chain.Client, endEpoch uint64, filter *iotexapi.LogsFilter, fn func(*iotextypes.Log) (bool, error)) error {
+	// the window is distributed after it ends
+	from, err := c.GetEpochHeight(ctx, endEpoch+1)
+	if err != nil {
+		return err
+	}
+	meta, err := c.GetChainMeta(ctx)
+	if err != nil {
+		return err
+	}
+	for done := false; from <= meta.Height && !done; from += reconcileBlocks {
+		logs, err := c.GetLogs(ctx, filter, from, reconcileBlocks)
+		if err != nil {
+			return err
+		}
+		for _, log := range logs {
+			found, err := fn(log)
+			if err != nil {
+				return err
+			}
+			done = done || found
+		}
+	}
+	return nil
+}
+
+// committedNamesOf returns the delegate names of a CommitDistributions log, or nil if it commits another window
+func committedNamesOf(hermesABI abi.ABI, log *iotextypes.Log, endEpoch uint64) ([]string, error) {
+	var event struct {
+		EndEpoch      *big.Int
+		DelegateNames [][32]byte
+	}
+	if err := hermesABI.Unpack(&event, "CommitDistributions", log.Data); err != nil {
+		return nil, err
+	}
+	if event.EndEpoch.Uint64() != endEpoch {
+		return nil, nil
+	}
+	names := make([]string, 0, len(event.DelegateNames))
+	for _, name := range event.DelegateNames {
+		names = append(names, string(bytes.TrimRight(name[:], "\x00")))
+	}
+	return names, nil
+}
+
+// transferOf returns the payment of a Multisend Transfer log
+func transferOf(multisendABI abi.ABI, log *iotextypes.Log) (*payment, error) {
+	if len(log.Topics) < 3 {
+		return nil, errors.New("invalid topics of transfer event")
+	}
+	var event struct {
+		Value *big.Int
+	}
+	if err := multisendABI.Unpack(&event, "Transfer", log.Data); err != nil {
+		return nil, err
+	}
+	return &payment{
+		to:         common.BytesToAddress(log.Topics[2]),
+		amount:     event.Value,
+		actionHash: hex.EncodeToString(log.ActHash),
+	}, nil
+}
+
+// WriteJSON writes the reconciliation as indented JSON
+func (r *Reconciliation) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteTable writes the reconciliation as human readable tables
+func (r *Reconciliation) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "End Epoch: %d, Plan Hash: %s, Committed: %t, Problems: %d\n\n", r.EndEpoch, r.PlanHash, r.Committed, r.Problems())
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DELEGATE\tPLANNED\tPAID\tDEPOSITS\tACTIONS")
+	for _, d := range r.Delegates {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\n", d.DelegateName, d.Planned, d.Paid, d.Deposits, d.Actions)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if r.FeeSweep != nil {
+		fmt.Fprintf(w, "\nFee Sweep: %s of fees %s to %s, Status: %s, Action: %s\n", r.FeeSweep.Amount, r.FeeSweep.Fees,
+			r.FeeSweep.Treasury, r.FeeSweep.Status, r.FeeSweep.ActionHash)
+	}
+	if len(r.Findings) == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tDELEGATE\tRECIPIENT\tEXPECTED\tACTUAL\tACTION")
+	for _, f := range r.Findings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Kind, f.DelegateName, f.Recipient, f.Expected, f.Actual, f.ActionHash)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestReconcile(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(
+		&mockanalytics.Delegate{
+			DelegateName:   "alpha",
+			StakingAddress: testAddress(1).String(),
+			Refund:         "0",
+			Rewards: []*mockanalytics.Reward{
+				{Voter: testAddress(1).String(), Amount: "100000"},
+				{Voter: testAddress(2).String(), Amount: "200000"},
+				{Voter: testAddress(3).String(), Amount: "300000"},
+			},
+		},
+		&mockanalytics.Delegate{
+			DelegateName:   "beta",
+			StakingAddress: testAddress(4).String(),
+			Refund:         "0",
+			Rewards:        []*mockanalytics.Reward{{Voter: testAddress(4).String(), Amount: "400000"}},
+		},
+	)
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.ChunkSize = 2
+	hermes := newFakeHermes(cfg, c)
+	hermes.buckets[common.BytesToAddress(testAddress(3).Bytes())] = 7
+	// voters 1 and 2 forward to the same address
+	for _, voter := range []byte{1, 2} {
+		hermes.forwards[common.BytesToAddress(testAddress(voter).Bytes())] = &ForwardService{Nonce: big.NewInt(1),
+			Destination: common.BytesToAddress(testAddress(20).Bytes()), StartEpoch: big.NewInt(0)}
+	}
+	ctx := context.Background()
+
+	// the forwarded payments are redirected, not duplicated, and the auto deposit is pending until the drop records
+	// are sent
+	require.NoError(Reward(ctx, cfg, c, nil))
+	r, err := Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.True(r.Committed)
+	require.Equal(0, r.Problems())
+	kinds := make(map[string][]string)
+	for _, f := range r.Findings {
+		kinds[f.Kind] = append(kinds[f.Kind], f.Recipient)
+	}
+	require.Len(kinds, 2)
+	require.ElementsMatch([]string{testAddress(1).String(), testAddress(2).String()}, kinds[FindingRedirected])
+	require.Equal([]string{testAddress(3).String()}, kinds[FindingPending])
+	require.Equal(&DelegateReconciliation{DelegateName: "alpha", Planned: 3, Paid: 3, Deposits: 1, Actions: 2}, r.Delegates[0])
+	require.Equal(&DelegateReconciliation{DelegateName: "beta", Planned: 1, Paid: 1, Actions: 1}, r.Delegates[1])
+
+	NewSender(cfg, c).Send(ctx)
+	r, err = Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.Len(r.Findings, 2)
+	require.Equal(0, r.Problems())
+
+	// a recipient paid again is a duplicated payment
+	voter := common.BytesToAddress(testAddress(4).Bytes())
+	require.NoError(sendRewards(ctx, cfg, c, "beta", big.NewInt(24), hermes.minTips,
+		[]common.Address{voter}, []*big.Int{big.NewInt(400000)}))
+	r, err = Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.Equal(1, r.Problems())
+	duplicated := r.Findings[len(r.Findings)-1]
+	require.Equal(FindingDuplicated, duplicated.Kind)
+	require.Equal("beta", duplicated.DelegateName)
+	require.Equal(testAddress(4).String(), duplicated.Recipient)
+	require.Equal("400000", duplicated.Actual)
+
+	var table bytes.Buffer
+	require.NoError(r.WriteTable(&table))
+	require.Contains(table.String(), "Committed: true, Problems: 1")
+}
+
+func TestReconcileByDestination(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "0",
+		Rewards: []*mockanalytics.Reward{
+			{Voter: testAddress(1).String(), Amount: "100000"},
+			{Voter: testAddress(2).String(), Amount: "200000"},
+			{Voter: testAddress(3).String(), Amount: "300000"},
+		},
+	})
+	defer analytics.Close()
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.ChunkSize = 1
+	hermes := newFakeHermes(cfg, c)
+	ctx := context.Background()
+
+	window, err := getDistribution(ctx, cfg, c)
+	require.NoError(err)
+	plan, err := loadPlan(ctx, cfg, c, window, false)
+	require.NoError(err)
+	d := plan.Delegates[0]
+	// the last recipient forwards its reward
+	last := common.HexToAddress(d.Recipients[2])
+	hermes.forwards[last] = &ForwardService{Nonce: big.NewInt(1),
+		Destination: common.BytesToAddress(testAddress(20).Bytes()), StartEpoch: big.NewInt(0)}
+
+	// the middle chunk is sent again before the last one, as if its first action was mined after the daemon resumed
+	for _, i := range []int{0, 1, 1} {
+		amount, ok := new(big.Int).SetString(d.Amounts[i], 10)
+		require.True(ok)
+		require.NoError(sendRewards(ctx, cfg, c, "alpha", window.endEpoch, window.minTips,
+			[]common.Address{common.HexToAddress(d.Recipients[i])}, []*big.Int{amount}))
+	}
+	hermes.distributedCount[stringToBytes32("alpha")] = 2
+	require.NoError(Reward(ctx, cfg, c, nil))
+
+	recipientOf := func(i int) string {
+		addr, err := evmAddrToIoAddr(common.HexToAddress(d.Recipients[i]))
+		require.NoError(err)
+		return addr
+	}
+	r, err := Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.Equal(1, r.Problems())
+	require.Len(r.Findings, 2)
+	require.Equal(FindingRedirected, r.Findings[0].Kind)
+	require.Equal(recipientOf(2), r.Findings[0].Recipient)
+	require.Equal(FindingDuplicated, r.Findings[1].Kind)
+	require.Equal(recipientOf(1), r.Findings[1].Recipient)
+	require.Equal(d.Amounts[1], r.Findings[1].Actual)
+
+	// a payment to an address the recipient does not forward to is misdirected
+	delete(hermes.forwards, last)
+	r, err = Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.Equal(2, r.Problems())
+	require.Len(r.Findings, 2)
+	require.Equal(&ReconciliationFinding{Kind: FindingMisdirected, DelegateName: "alpha", Recipient: recipientOf(2),
+		Expected: recipientOf(2), Actual: testAddress(20).String(), ActionHash: r.Findings[0].ActionHash}, r.Findings[0])
+	require.Equal(FindingDuplicated, r.Findings[1].Kind)
+}
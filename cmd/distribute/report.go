@@ -0,0 +1,205 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"text/tabwriter"
+
+	"github.com/iotexproject/iotex-address/address"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// Report is the preview of a distribution produced by a dry run
+type Report struct {
+	StartEpoch uint64            `json:"startEpoch"`
+	EndEpoch   uint64            `json:"endEpoch"`
+	MinTips    string            `json:"minTips"`
+	ChunkSize  int               `json:"chunkSize"`
+	TotalValue string            `json:"totalValue"`
+	Delegates  []*DelegateReport `json:"delegates"`
+}
+
+// DelegateReport is the preview of the distribution of a delegate
+type DelegateReport struct {
+	DelegateName     string         `json:"delegateName"`
+	StartEpoch       uint64         `json:"startEpoch"`
+	ServiceFee       string         `json:"serviceFee"`
+	FeePolicy        string         `json:"feePolicy"`
+	Refund           string         `json:"refund"`
+	RecipientCount   int            `json:"recipientCount"`
+	DistributedCount uint64         `json:"distributedCount"`
+	Chunks           []*ChunkReport `json:"chunks"`
+}
+
+// ChunkReport is the preview of a distributeRewards call
+type ChunkReport struct {
+	Index      int                `json:"index"`
+	Value      string             `json:"value"`
+	Recipients []*RecipientReport `json:"recipients"`
+}
+
+// RecipientReport is the preview of the reward of a recipient. ForwardAddress is the destination the Hermes contract
+// pays its transfer to instead, if the recipient registers a forward.
+type RecipientReport struct {
+	Address        string `json:"address"`
+	Amount         string `json:"amount"`
+	AutoDeposit    bool   `json:"autoDeposit"`
+	BucketID       int64  `json:"bucketID"`
+	ForwardAddress string `json:"forwardAddress,omitempty"`
+}
+
+// Simulate runs the distribution math for the next window without sending any action. The chunks are the ones
+// Reward would send, cut from the plan of the window after the recipients already distributed.
+func Simulate(ctx context.Context, cfg *config.Config, c chain.Client) (*Report, error) {
+	window, err := getDistribution(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	return buildReport(ctx, cfg, c, window)
+}
+
+func buildReport(ctx context.Context, cfg *config.Config, c chain.Client, window *distributionWindow) (*Report, error) {
+	plan, err := loadPlan(ctx, cfg, c, window, true)
+	if err != nil {
+		return nil, err
+	}
+	distributions, err := plan.distributions()
+	if err != nil {
+		return nil, err
+	}
+	ch, err := newChunker(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	fr, err := NewForwardRegistration(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		StartEpoch: window.startEpoch,
+		EndEpoch:   window.endEpoch.Uint64(),
+		MinTips:    window.minTips.String(),
+		ChunkSize:  ch.maxSize,
+	}
+	totalValue := big.NewInt(0)
+	for i, dist := range distributions {
+		distributedCount, err := getDistributedCount(ctx, cfg, c, dist.DelegateName)
+		if err != nil {
+			return nil, err
+		}
+		delegate := &DelegateReport{
+			DelegateName:     dist.DelegateName,
+			StartEpoch:       dist.StartEpoch,
+			ServiceFee:       dist.ServiceFee.String(),
+			FeePolicy:        dist.FeePolicy,
+			Refund:           dist.Refund.String(),
+			RecipientCount:   len(dist.RecipientList),
+			DistributedCount: distributedCount,
+		}
+		for start := int(distributedCount); start < len(dist.RecipientList); {
+			end := plan.Delegates[i].nextChunkEnd(start)
+			recipients, amounts := dist.RecipientList[start:end], dist.AmountList[start:end]
+			size, err := ch.next(ctx, dist.DelegateName, window.endEpoch, window.minTips, recipients, amounts)
+			if err != nil {
+				return nil, err
+			}
+			recipients, amounts = recipients[:size], amounts[:size]
+			start += size
+
+			bucketIDs := getBucketIDs(ctx, cfg, c, recipients)
+			value := new(big.Int).Set(window.minTips)
+			chunk := &ChunkReport{Index: len(delegate.Chunks)}
+			for j, voter := range recipients {
+				addr, err := address.FromBytes(voter[:])
+				if err != nil {
+					return nil, err
+				}
+				autoDeposit := bucketIDs[j] != -1
+				if !autoDeposit {
+					value.Add(value, amounts[j])
+				}
+				recipient := &RecipientReport{
+					Address:     addr.String(),
+					Amount:      amounts[j].String(),
+					AutoDeposit: autoDeposit,
+					BucketID:    bucketIDs[j],
+				}
+				forward, err := fr.ForwardAddress(ctx, voter, report.EndEpoch)
+				if err != nil {
+					return nil, err
+				}
+				if forward != voter {
+					if recipient.ForwardAddress, err = evmAddrToIoAddr(forward); err != nil {
+						return nil, err
+					}
+				}
+				chunk.Recipients = append(chunk.Recipients, recipient)
+			}
+			chunk.Value = value.String()
+			totalValue.Add(totalValue, value)
+			delegate.Chunks = append(delegate.Chunks, chunk)
+		}
+		report.Delegates = append(report.Delegates, delegate)
+	}
+	report.TotalValue = totalValue.String()
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteTable writes the report as human readable tables
+func (r *Report) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Distribution Start Epoch: %d\n", r.StartEpoch)
+	fmt.Fprintf(w, "Distribution End Epoch: %d\n", r.EndEpoch)
+	fmt.Fprintf(w, "Min Tips: %s, Chunk Size: %d, Total Value: %s\n\n", r.MinTips, r.ChunkSize, r.TotalValue)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DELEGATE\tSTART EPOCH\tSERVICE FEE\tFEE POLICY\tREFUND\tRECIPIENTS\tDISTRIBUTED\tCHUNKS")
+	for _, d := range r.Delegates {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%d\t%d\n", d.DelegateName, d.StartEpoch, d.ServiceFee, d.FeePolicy, d.Refund,
+			d.RecipientCount, d.DistributedCount, len(d.Chunks))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, d := range r.Delegates {
+		fmt.Fprintf(w, "\nDelegate Name: %s\n", d.DelegateName)
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "CHUNK\tRECIPIENT\tAMOUNT\tAUTO DEPOSIT\tBUCKET\tFORWARD TO")
+		for _, chunk := range d.Chunks {
+			for _, recipient := range chunk.Recipients {
+				bucket, forward := "-", "-"
+				if recipient.AutoDeposit {
+					bucket = fmt.Sprintf("%d", recipient.BucketID)
+				}
+				if recipient.ForwardAddress != "" {
+					forward = recipient.ForwardAddress
+				}
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%t\t%s\t%s\n", chunk.Index, recipient.Address, recipient.Amount,
+					recipient.AutoDeposit, bucket, forward)
+			}
+			fmt.Fprintf(tw, "%d\tmsg.value\t%s\t\t\t\n", chunk.Index, chunk.Value)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
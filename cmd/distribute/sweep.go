@@ -0,0 +1,219 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+// SweepCmd is the sweep command
+var SweepCmd = &cobra.Command{
+	Use:   "sweep",
+	Short: "Transfer the service fees of a committed window to the fee treasury",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if cfg.Distribution.FeeTreasury == "" {
+			return errors.New("distribution.feeTreasury is not defined")
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		return SweepFees(context.Background(), cfg, c, sweepEndEpoch)
+	},
+}
+
+var sweepEndEpoch uint64
+
+func init() {
+	SweepCmd.Flags().Uint64Var(&sweepEndEpoch, "end-epoch", 0, "end epoch of the window")
+	SweepCmd.MarkFlagRequired("end-epoch")
+}
+
+// SweepFees transfers the service fees recorded in the cycle ending at endEpoch to the fee treasury, if it is set. A
+// sweep sent earlier, or found by its nonce if sending it failed, is waited for, and sent again only if it is reverted,
+// or dropped and the account nonce shows it can no longer be mined.
+func SweepFees(ctx context.Context, cfg *config.Config, c chain.Client, endEpoch uint64) error {
+	if cfg.Distribution.FeeTreasury == "" {
+		return nil
+	}
+	ctx = logger.WithFields(ctx, logger.EndEpoch(endEpoch))
+	sweep, err := dao.FindFeeSweepByEndEpoch(endEpoch)
+	if err != nil {
+		return err
+	}
+	if sweep != nil && sweep.Status == dao.FeeSweepCompleted {
+		return nil
+	}
+	if sweep != nil && sweep.Hash == "" && sweep.Nonce != 0 {
+		// sending the sweep may fail after the node accepted it, which leaves its nonce as the only trace of it
+		if sweep.Hash, err = sweepHashOf(ctx, c, sweep); err != nil {
+			return err
+		}
+	}
+	waiter := chain.NewReceiptWaiter(cfg.Receipt)
+	if sweep != nil && sweep.Hash != "" {
+		h, err := hash.HexStringToHash256(sweep.Hash)
+		if err != nil {
+			return err
+		}
+		_, err = waiter.Wait(ctx, c, h)
+		switch cause := errors.Cause(err); {
+		case err == nil:
+			return completeSweep(ctx, sweep)
+		case cause == chain.ErrDropped:
+			// the node may miss an action still in the pool of another node, so the sweep is sent again only if the
+			// old one can no longer be mined, or the new one takes its nonce
+			nonce, pendingNonce, nonceErr := c.GetNonce(ctx)
+			if nonceErr != nil {
+				return nonceErr
+			}
+			if nonce < sweep.Nonce && pendingNonce > sweep.Nonce {
+				return errors.Wrapf(err, "fee sweep of nonce %d may still be mined", sweep.Nonce)
+			}
+		case cause != chain.ErrReverted:
+			return errors.Wrap(err, "fee sweep is not mined")
+		}
+		logger.Ctx(ctx).Warn("sending fee sweep again", logger.ActionHash(h), zap.Error(err))
+	}
+
+	amount, err := serviceFeesOf(endEpoch)
+	if err != nil {
+		return err
+	}
+	if amount.Sign() == 0 {
+		return nil
+	}
+	if sweep == nil {
+		sweep = &dao.FeeSweep{EndEpoch: endEpoch, Status: dao.FeeSweepNew}
+	}
+	sweep.Treasury, sweep.Amount = cfg.Distribution.FeeTreasury, amount.String()
+	if err := sweep.Save(nil); err != nil {
+		return errors.Wrap(err, "failed to save fee sweep")
+	}
+
+	to, err := address.FromString(cfg.Distribution.FeeTreasury)
+	if err != nil {
+		return err
+	}
+	gas, err := chain.NewGasEstimator(cfg.Gas).Transfer(ctx, c, to, amount)
+	if err == nil {
+		// the nonce is saved before sending, so a sweep accepted by the node is found by it even if sending fails
+		_, sweep.Nonce, err = c.GetNonce(ctx)
+	}
+	if err == nil {
+		sweep.Hash, sweep.Status = "", dao.FeeSweepSent
+		if err := sweep.Save(nil); err != nil {
+			return errors.Wrap(err, "failed to save fee sweep")
+		}
+		var h hash.Hash256
+		if h, err = c.Transfer(ctx, to, amount, gas); err == nil {
+			sweep.Hash = hex.EncodeToString(h[:])
+			if err := sweep.Save(nil); err != nil {
+				return errors.Wrap(err, "failed to save fee sweep")
+			}
+			_, err = waiter.Wait(ctx, c, h)
+		}
+	}
+	if err != nil {
+		err = errors.Wrap(err, "fee sweep failed")
+		if errors.Cause(err) != chain.ErrNotMined {
+			sweep.Status, sweep.ErrorMessage = dao.FeeSweepError, err.Error()
+			if err := sweep.Save(nil); err != nil {
+				logger.Ctx(ctx).Error("failed to save fee sweep", zap.Error(err))
+			}
+		}
+		notify.Send(ctx, &notify.Event{
+			Type:     notify.EventFeeSweepFailed,
+			Message:  err.Error(),
+			EndEpoch: endEpoch,
+			Fields:   map[string]string{"treasury": sweep.Treasury, "amount": sweep.Amount},
+		})
+		return err
+	}
+	return completeSweep(ctx, sweep)
+}
+
+// sweepHashOf returns the hash of the action mined with the nonce of a sweep whose sending failed if it is the sweep,
+// or an empty hash if the node never accepted the sweep or another action takes its nonce
+func sweepHashOf(ctx context.Context, c chain.Client, sweep *dao.FeeSweep) (string, error) {
+	nonce, pendingNonce, err := c.GetNonce(ctx)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case nonce < sweep.Nonce && pendingNonce > sweep.Nonce:
+		return "", fmt.Errorf("fee sweep of nonce %d may still be mined", sweep.Nonce)
+	case nonce < sweep.Nonce:
+		return "", nil
+	}
+	info, err := c.GetActionByNonce(ctx, sweep.Nonce)
+	if err != nil || info == nil {
+		return "", err
+	}
+	transfer := info.Action.GetCore().GetTransfer()
+	if transfer == nil || transfer.Recipient != sweep.Treasury || transfer.Amount != sweep.Amount {
+		return "", nil
+	}
+	return info.ActHash, nil
+}
+
+// completeSweep records the sweep as completed
+func completeSweep(ctx context.Context, sweep *dao.FeeSweep) error {
+	sweep.Status, sweep.ErrorMessage = dao.FeeSweepCompleted, ""
+	if err := sweep.Save(nil); err != nil {
+		return errors.Wrap(err, "failed to save fee sweep")
+	}
+	logger.Ctx(ctx).Info("swept service fees", zap.String("treasury", sweep.Treasury),
+		zap.String("amount", sweep.Amount), zap.String("actionHash", sweep.Hash))
+	return nil
+}
+
+// serviceFeesOf returns the total of the service fees recorded in the cycle ending at endEpoch
+func serviceFeesOf(endEpoch uint64) (*big.Int, error) {
+	fees, err := dao.FindServiceFeesByEndEpoch(endEpoch)
+	if err != nil {
+		return nil, err
+	}
+	total := big.NewInt(0)
+	for _, fee := range fees {
+		amount, ok := new(big.Int).SetString(fee.Fee, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid service fee %s of delegate %s", fee.Fee, fee.DelegateName)
+		}
+		total.Add(total, amount)
+	}
+	return total, nil
+}
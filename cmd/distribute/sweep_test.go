@@ -0,0 +1,135 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+func TestSweepFees(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	analytics := newAnalyticsServer(&mockanalytics.Delegate{
+		DelegateName:   "alpha",
+		StakingAddress: testAddress(1).String(),
+		Refund:         "1000",
+		Rewards:        []*mockanalytics.Reward{{Voter: testAddress(2).String(), Amount: "100000"}},
+	})
+	defer analytics.Close()
+	treasury := testAddress(20).String()
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Distribution.WaiverThreshold = 100
+	cfg.Distribution.BaseCharge = config.NewBigInt(big.NewInt(300))
+	cfg.Distribution.FeeTreasury = treasury
+	newFakeHermes(cfg, c)
+	ctx := context.Background()
+
+	sink := notify.NewSink()
+	alerts := httptest.NewServer(sink)
+	defer alerts.Close()
+	notify.SetNotifiers(notify.NewWebhook(alerts.URL))
+	defer notify.SetNotifiers()
+
+	// the fees of the committed window are swept to the treasury
+	require.NoError(Reward(ctx, cfg, c, nil))
+	actions := c.Actions()
+	last := actions[len(actions)-1]
+	require.Equal(chain.ActionTransfer, last.Type)
+	require.Equal(treasury, last.To)
+	require.Equal(big.NewInt(300), last.Amount)
+	sweep, err := dao.FindFeeSweepByEndEpoch(24)
+	require.NoError(err)
+	require.Equal(dao.FeeSweepCompleted, sweep.Status)
+	require.Equal("300", sweep.Amount)
+
+	r, err := Reconcile(ctx, cfg, c, 24)
+	require.NoError(err)
+	require.Empty(r.Findings)
+	require.Equal(&FeeSweepReconciliation{Treasury: treasury, Fees: "300", Amount: "300", Status: dao.FeeSweepCompleted,
+		ActionHash: sweep.Hash}, r.FeeSweep)
+
+	// a swept window is not swept again
+	require.NoError(SweepFees(ctx, cfg, c, 24))
+	require.Len(c.Actions(), len(actions))
+
+	// a failed sweep is alerted, recorded and sent again
+	require.NoError((&dao.ServiceFee{EndEpoch: 48, DelegateName: "alpha", Policy: "linear", Fee: "50", Refund: "0"}).Save(nil))
+	c.FailNext(chain.ActionTransfer, errors.New("rejected"))
+	require.Error(SweepFees(ctx, cfg, c, 48))
+	require.Equal([]string{notify.EventFeeSweepFailed}, sink.Types())
+	sweep, err = dao.FindFeeSweepByEndEpoch(48)
+	require.NoError(err)
+	require.Equal(dao.FeeSweepError, sweep.Status)
+	require.Contains(sweep.ErrorMessage, "rejected")
+
+	// a dropped sweep is sent again only once a later action confirms a nonce past it
+	c.DropNext()
+	require.Error(SweepFees(ctx, cfg, c, 48))
+	require.Error(SweepFees(ctx, cfg, c, 48))
+	require.Len(c.Actions(), len(actions)+1)
+	_, err = c.Transfer(ctx, testAddress(21), big.NewInt(1), chain.Gas{})
+	require.NoError(err)
+	require.NoError(SweepFees(ctx, cfg, c, 48))
+	sweep, err = dao.FindFeeSweepByEndEpoch(48)
+	require.NoError(err)
+	require.Equal(dao.FeeSweepCompleted, sweep.Status)
+	var swept []*big.Int
+	for _, act := range c.Actions()[len(actions):] {
+		if act.To == treasury {
+			swept = append(swept, act.Amount)
+		}
+	}
+	require.Equal([]*big.Int{big.NewInt(50), big.NewInt(50)}, swept)
+
+	// a sweep accepted although sending it failed is not sent again while it may be mined, and is found by its nonce
+	// once mined
+	sent := len(c.Actions())
+	require.NoError((&dao.ServiceFee{EndEpoch: 72, DelegateName: "alpha", Policy: "linear", Fee: "40", Refund: "0"}).Save(nil))
+	c.DropNext()
+	c.LoseNext(errors.New("timeout"))
+	require.Error(SweepFees(ctx, cfg, c, 72))
+	sweep, err = dao.FindFeeSweepByEndEpoch(72)
+	require.NoError(err)
+	require.Empty(sweep.Hash)
+	require.Equal(c.Actions()[sent].Nonce, sweep.Nonce)
+	require.Error(SweepFees(ctx, cfg, c, 72))
+	require.Len(c.Actions(), sent+1)
+
+	require.NoError((&dao.ServiceFee{EndEpoch: 96, DelegateName: "alpha", Policy: "linear", Fee: "30", Refund: "0"}).Save(nil))
+	c.LoseNext(errors.New("timeout"))
+	require.Error(SweepFees(ctx, cfg, c, 96))
+	require.NoError(SweepFees(ctx, cfg, c, 96))
+	sweep, err = dao.FindFeeSweepByEndEpoch(96)
+	require.NoError(err)
+	require.Equal(dao.FeeSweepCompleted, sweep.Status)
+	require.Equal(hex.EncodeToString(c.Actions()[sent+1].Hash[:]), sweep.Hash)
+
+	// the pending sweep is sent again once a later action is mined without it
+	require.NoError(SweepFees(ctx, cfg, c, 72))
+	sweep, err = dao.FindFeeSweepByEndEpoch(72)
+	require.NoError(err)
+	require.Equal(dao.FeeSweepCompleted, sweep.Status)
+	swept = nil
+	for _, act := range c.Actions()[sent:] {
+		swept = append(swept, act.Amount)
+	}
+	require.Equal([]*big.Int{big.NewInt(40), big.NewInt(30), big.NewInt(40)}, swept)
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+)
+
+// Window is the epoch range of the next distribution
+type Window struct {
+	StartEpoch   uint64
+	EndEpoch     uint64
+	CurrentEpoch uint64
+}
+
+// NextWindow returns the window of cfg.Schedule.WindowEpochs epochs following the last committed one
+func NextWindow(ctx context.Context, cfg *config.Config, c chain.Client) (*Window, error) {
+	lastEndEpoch, err := GetLastEndEpoch(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := c.GetChainMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	startEpoch := lastEndEpoch + 1
+	return &Window{
+		StartEpoch:   startEpoch,
+		EndEpoch:     startEpoch + cfg.Schedule.WindowEpochs - 1,
+		CurrentEpoch: meta.Epoch.Num,
+	}, nil
+}
+
+// Due returns whether the end epoch of the window is at least cfg.Schedule.FinalityLag epochs old
+func (w *Window) Due(cfg *config.Config) bool {
+	return w.EndEpoch+cfg.Schedule.FinalityLag <= w.CurrentEpoch
+}
+
+// Wait returns the time until the window is due
+func (w *Window) Wait(cfg *config.Config) time.Duration {
+	if w.Due(cfg) {
+		return 0
+	}
+	return time.Duration(w.EndEpoch+cfg.Schedule.FinalityLag-w.CurrentEpoch) * cfg.Schedule.EpochDuration
+}
+
+// getBookkeeping returns the distributions of the window. A delegate with its own schedule is left out until its
+// window has passed since its last distribution, and then covers all the epochs from it.
+func getBookkeeping(ctx context.Context, cfg *config.Config, c chain.Client, window *Window) ([]*DistributionInfo, error) {
+	source, err := NewBookkeepingSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	rewardAddress := c.Account().Address().String()
+	bookkeeping, err := source.Bookkeeping(ctx, window.StartEpoch, window.EndEpoch-window.StartEpoch+1, rewardAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for name := range cfg.Schedule.Delegates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var delegates, extended []*DelegateBookkeeping
+	extendedStart := make(map[string]uint64)
+	for _, d := range bookkeeping.Delegates {
+		if _, ok := cfg.Schedule.Delegates[d.DelegateName]; !ok {
+			delegates = append(delegates, d)
+		}
+	}
+	for _, name := range names {
+		startEpoch, due, err := delegateStartEpoch(ctx, cfg, c, name, window)
+		if err != nil {
+			return nil, err
+		}
+		if !due {
+			logger.Ctx(ctx).Info("skipped delegate until its window passes", logger.Delegate(name),
+				zap.Uint64("windowEpochs", cfg.Schedule.Delegates[name].WindowEpochs))
+			continue
+		}
+		delegateBookkeeping := bookkeeping
+		if startEpoch != window.StartEpoch {
+			delegateBookkeeping, err = source.Bookkeeping(ctx, startEpoch, window.EndEpoch-startEpoch+1, rewardAddress)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, d := range delegateBookkeeping.Delegates {
+			if d.DelegateName == name {
+				extended = append(extended, d)
+				extendedStart[name] = startEpoch
+			}
+		}
+	}
+
+	overrides, err := addressOverridesOf(window.EndEpoch)
+	if err != nil {
+		return nil, err
+	}
+	distributions, err := distributionsOf(cfg, &Bookkeeping{
+		StartEpoch:    window.StartEpoch,
+		EpochCount:    window.EndEpoch - window.StartEpoch + 1,
+		RewardAddress: rewardAddress,
+		Delegates:     append(delegates, extended...),
+	}, overrides)
+	if err != nil {
+		return nil, err
+	}
+	for _, dist := range distributions {
+		if startEpoch, ok := extendedStart[dist.DelegateName]; ok {
+			dist.StartEpoch = startEpoch
+		}
+	}
+	return distributions, nil
+}
+
+// delegateStartEpoch returns the first epoch not distributed to the delegate, and whether its window has passed by
+// the end of window. A delegate is distributed in the first window its own window has passed by, so the scan stops
+// at the windows ending before the delegate window preceding window, and a delegate not distributed since starts its
+// schedule from window.
+func delegateStartEpoch(ctx context.Context, cfg *config.Config, c chain.Client, name string, window *Window) (uint64, bool, error) {
+	endEpochCount, err := getEndEpochCount(ctx, cfg, c)
+	if err != nil {
+		return 0, false, err
+	}
+	windowEpochs := cfg.Schedule.Delegates[name].WindowEpochs
+	for i := endEpochCount; i > 0; i-- {
+		endEpoch, err := getEndEpoch(ctx, cfg, c, i-1)
+		if err != nil {
+			return 0, false, err
+		}
+		if endEpoch+windowEpochs < window.StartEpoch {
+			break
+		}
+		count, _, err := getCommittedDistribution(ctx, cfg, c, name, endEpoch)
+		if err != nil {
+			return 0, false, err
+		}
+		if count > 0 {
+			return endEpoch + 1, window.EndEpoch-endEpoch >= windowEpochs, nil
+		}
+	}
+	return window.StartEpoch, true, nil
+}
+
+// OwedWindows returns the due windows following the last committed one, at most max windows if max is positive. A
+// bookkeeping file only covers one window, so it returns an error if several windows are owed with it.
+func OwedWindows(ctx context.Context, cfg *config.Config, c chain.Client, max int) ([]*Window, error) {
+	window, err := NextWindow(ctx, cfg, c)
+	if err != nil {
+		return nil, err
+	}
+	var windows []*Window
+	for window.Due(cfg) && (max <= 0 || len(windows) < max) {
+		windows = append(windows, window)
+		window = &Window{
+			StartEpoch:   window.EndEpoch + 1,
+			EndEpoch:     window.EndEpoch + cfg.Schedule.WindowEpochs,
+			CurrentEpoch: window.CurrentEpoch,
+		}
+	}
+	if cfg.Bookkeeping.Source == BookkeepingSourceFile && len(windows) > 1 {
+		return nil, fmt.Errorf("bookkeeping file holds one window but %d windows are owed, catch up with "+
+			"catch-up --max-windows 1 and the bookkeeping file of every window in turn", len(windows))
+	}
+	return windows, nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package distribute
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+func TestWindow(t *testing.T) {
+	require := require.New(t)
+
+	cfg := &config.Config{Schedule: config.Schedule{WindowEpochs: 168, FinalityLag: 3, EpochDuration: time.Hour}}
+	w := &Window{StartEpoch: 1, EndEpoch: 168, CurrentEpoch: 160}
+	require.False(w.Due(cfg))
+	require.Equal(11*time.Hour, w.Wait(cfg))
+	w.CurrentEpoch = 171
+	require.True(w.Due(cfg))
+	require.Equal(time.Duration(0), w.Wait(cfg))
+}
+
+func TestDelegateSchedule(t *testing.T) {
+	require := require.New(t)
+	connectTestDatabase(require)
+
+	daily, weekly := testAddress(1), testAddress(2)
+	fixture := &mockanalytics.Fixture{}
+	for epoch := uint64(1); epoch <= 72; epoch++ {
+		fixture.Epochs = append(fixture.Epochs, &mockanalytics.Epoch{Epoch: epoch, Delegates: []*mockanalytics.Delegate{
+			{DelegateName: "daily", StakingAddress: daily.String(), Refund: "0",
+				Rewards: []*mockanalytics.Reward{{Voter: daily.String(), Amount: "10"}}},
+			{DelegateName: "weekly", StakingAddress: weekly.String(), Refund: "0",
+				Rewards: []*mockanalytics.Reward{{Voter: weekly.String(), Amount: "10"}}},
+		}})
+	}
+	analytics := httptest.NewServer(mockanalytics.NewServer(fixture))
+	defer analytics.Close()
+
+	cfg, c := newTestConfig(require, analytics.URL)
+	cfg.Schedule = config.Schedule{
+		WindowEpochs:  24,
+		FinalityLag:   2,
+		EpochDuration: time.Hour,
+		Delegates:     map[string]config.DelegateSchedule{"weekly": {WindowEpochs: 48}},
+	}
+	hermes := newFakeHermes(cfg, c)
+	received := func(addr common.Address) string {
+		if amount, ok := hermes.received[addr]; ok {
+			return amount.String()
+		}
+		return "0"
+	}
+	dailyAddr, weeklyAddr := common.BytesToAddress(daily.Bytes()), common.BytesToAddress(weekly.Bytes())
+
+	// the window is not final yet
+	c.SetChainMeta(100, 25)
+	require.Error(Reward(context.Background(), cfg, c, nil))
+
+	// weekly starts its schedule in the first window
+	c.SetChainMeta(100, 26)
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+	require.Equal("240", received(dailyAddr))
+	require.Equal("240", received(weeklyAddr))
+
+	// weekly is skipped in the second window
+	c.SetChainMeta(100, 50)
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+	require.Equal("480", received(dailyAddr))
+	require.Equal("240", received(weeklyAddr))
+
+	// weekly gets the rewards of both windows in the third one
+	c.SetChainMeta(100, 74)
+	report, err := Simulate(context.Background(), cfg, c)
+	require.NoError(err)
+	require.Len(report.Delegates, 2)
+	require.Equal(uint64(49), report.Delegates[0].StartEpoch)
+	require.Equal(uint64(25), report.Delegates[1].StartEpoch)
+	require.NoError(Reward(context.Background(), cfg, c, nil))
+	require.Equal("720", received(dailyAddr))
+	require.Equal("720", received(weeklyAddr))
+	require.Len(hermes.endEpochs, 3)
+}
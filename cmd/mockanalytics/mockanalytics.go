@@ -0,0 +1,38 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package mockanalytics
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/spf13/cobra"
+
+	"github.com/iotexproject/iotex-hermes/mockanalytics"
+)
+
+// MockAnalyticsCmd is the mock-analytics command
+var MockAnalyticsCmd = &cobra.Command{
+	Use:   "mock-analytics FIXTURE",
+	Short: "Serve the analytics hermes query from a fixture file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		fixture, err := mockanalytics.LoadFixture(args[0])
+		if err != nil {
+			return err
+		}
+		log.Printf("serving %d epochs of %s on %s\n", len(fixture.Epochs), args[0], listenAddress)
+		return http.ListenAndServe(listenAddress, mockanalytics.NewServer(fixture))
+	},
+}
+
+var listenAddress string
+
+func init() {
+	MockAnalyticsCmd.Flags().StringVar(&listenAddress, "listen", "127.0.0.1:8089", "address to listen on")
+}
@@ -11,6 +11,9 @@ import (
 
 	"github.com/iotexproject/iotex-hermes/cmd/claim"
 	"github.com/iotexproject/iotex-hermes/cmd/distribute"
+	"github.com/iotexproject/iotex-hermes/cmd/mockanalytics"
+	"github.com/iotexproject/iotex-hermes/cmd/run"
+	"github.com/iotexproject/iotex-hermes/config"
 )
 
 // RootCmd represents the base command when called without any subcommands
@@ -21,6 +24,20 @@ var RootCmd = &cobra.Command{
 }
 
 func init() {
+	RootCmd.PersistentFlags().StringVarP(&config.File, "config", "c", "",
+		"config file, every value can be overridden by its environment variable")
 	RootCmd.AddCommand(claim.ClaimCmd)
 	RootCmd.AddCommand(distribute.DistributeCmd)
+	RootCmd.AddCommand(distribute.SendCmd)
+	RootCmd.AddCommand(distribute.BookkeepingCmd)
+	RootCmd.AddCommand(distribute.ReconcileCmd)
+	RootCmd.AddCommand(distribute.HistoryCmd)
+	RootCmd.AddCommand(distribute.ReportCmd)
+	RootCmd.AddCommand(distribute.SweepCmd)
+	RootCmd.AddCommand(distribute.OverrideCmd)
+	RootCmd.AddCommand(distribute.ForwardCmd)
+	RootCmd.AddCommand(run.RunCmd)
+	RootCmd.AddCommand(run.StatusCmd)
+	RootCmd.AddCommand(run.CatchUpCmd)
+	RootCmd.AddCommand(mockanalytics.MockAnalyticsCmd)
 }
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package run
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/cmd/distribute"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+// CatchUpCmd is the catch-up command
+var CatchUpCmd = &cobra.Command{
+	Use:   "catch-up",
+	Short: "Plan and distribute all the windows owed since the last distribution with a single claim",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		ctx := context.Background()
+
+		plan, err := distribute.PlanCatchUp(ctx, cfg, c, catchUpMaxWindows)
+		if err != nil {
+			return err
+		}
+		switch catchUpOutput {
+		case "json":
+			err = plan.WriteJSON(cmd.OutOrStdout())
+		case "table":
+			err = plan.WriteTable(cmd.OutOrStdout())
+		default:
+			err = fmt.Errorf("unknown output format %s", catchUpOutput)
+		}
+		if err != nil || catchUpDryRun || len(plan.Windows) == 0 {
+			return err
+		}
+
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+		return CatchUp(ctx, cfg, c, plan)
+	},
+}
+
+var (
+	catchUpDryRun     bool
+	catchUpOutput     string
+	catchUpMaxWindows int
+)
+
+func init() {
+	CatchUpCmd.Flags().BoolVar(&catchUpDryRun, "dry-run", false, "show the plan without sending any action")
+	CatchUpCmd.Flags().StringVarP(&catchUpOutput, "output", "o", "table", "plan format, table or json")
+	CatchUpCmd.Flags().IntVar(&catchUpMaxWindows, "max-windows", 0, "distribute at most this many windows, 0 for all")
+}
+
+// CatchUp runs the cycles of the windows of plan in order, claiming the rewards once and committing every window. An
+// unfinished cycle of an earlier window is finished first.
+func CatchUp(ctx context.Context, cfg *config.Config, c chain.Client, plan *distribute.CatchUpPlan) error {
+	windows := make([]*distribute.Window, 0, len(plan.Windows))
+	for _, w := range plan.Windows {
+		windows = append(windows, &distribute.Window{StartEpoch: w.StartEpoch, EndEpoch: w.EndEpoch})
+	}
+	if _, err := scheduleCycles(windows); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		cycle, err := dao.FindUnfinishedCycle()
+		if err != nil {
+			return err
+		}
+		if cycle == nil {
+			return nil
+		}
+		ctx := logger.WithFields(ctx, logger.EndEpoch(cycle.EndEpoch))
+		logger.Ctx(ctx).Info("running cycle", zap.String("status", cycle.Status))
+		if err := runCycle(ctx, cfg, c, cycle); err != nil {
+			if err := cycle.Fail(err); err != nil {
+				logger.Ctx(ctx).Error("failed to save cycle", zap.Error(err))
+			}
+			return fmt.Errorf("cycle for end epoch %d failed in status %s: %v", cycle.EndEpoch, cycle.Status, err)
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,359 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package run
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/claim"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/cmd/distribute"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/health"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/metrics"
+	"github.com/iotexproject/iotex-hermes/notify"
+)
+
+// maxRetry is the number of consecutive failures tolerated before the daemon exits
+const maxRetry = 3
+
+// RunCmd is the run command
+var RunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the claim, distribute and send loop as a daemon",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := logger.Init(cfg.Log); err != nil {
+			return err
+		}
+		notify.Init(cfg.Alerts)
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return fmt.Errorf("create database error: %v", err)
+		}
+		c, conn, err := chain.Connect(cfg)
+		if err != nil {
+			return fmt.Errorf("construct grpc connection error: %v", err)
+		}
+		defer conn.Close()
+		if cfg.Metrics.Listen != "" {
+			server, err := serve(cfg, c)
+			if err != nil {
+				return err
+			}
+			defer server.Close()
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+			<-sig
+			logger.L().Info("shutting down after the current step")
+			cancel()
+		}()
+		return Run(ctx, cfg, c)
+	},
+}
+
+// StatusCmd is the status command
+var StatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the progress of the latest distribution cycles",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := config.Load(config.File)
+		if err != nil {
+			return err
+		}
+		if err := dao.ConnectDatabase(&cfg.Database); err != nil {
+			return err
+		}
+		cycles, err := dao.FindCyclesByLimit(statusLimit)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		for _, cycle := range cycles {
+			fmt.Fprintf(out, "Start Epoch: %d, End Epoch: %d, Status: %s, Distributed Delegates: %d, Failures: %d, Updated At: %s\n",
+				cycle.StartEpoch, cycle.EndEpoch, cycle.Status, cycle.DistributedDelegates, cycle.Failures,
+				cycle.UpdatedAt.Format(time.RFC3339))
+			if cycle.ErrorMessage != "" {
+				fmt.Fprintf(out, "  Last Error: %s\n", cycle.ErrorMessage)
+			}
+			fees, err := dao.FindServiceFeesByEndEpoch(cycle.EndEpoch)
+			if err != nil {
+				return err
+			}
+			for _, fee := range fees {
+				fmt.Fprintf(out, "  Service Fee: %s %s (%s), Refund: %s\n", fee.DelegateName, fee.Fee, fee.Policy, fee.Refund)
+			}
+		}
+		return nil
+	},
+}
+
+var statusLimit int32
+
+func init() {
+	StatusCmd.Flags().Int32VarP(&statusLimit, "limit", "n", 10, "number of cycles to show")
+}
+
+// Run claims and distributes rewards with c every time a distribution window is due, until ctx is done. The database
+// must be connected.
+func Run(ctx context.Context, cfg *config.Config, c chain.Client) error {
+	retry := 0
+	for ctx.Err() == nil {
+		if retry == maxRetry {
+			err := fmt.Errorf("retry %d times failure, exit", maxRetry)
+			notify.Send(ctx, &notify.Event{Type: notify.EventRetryExhausted, Message: err.Error()})
+			return err
+		}
+		collectMetrics(ctx, cfg, c)
+		cycle, err := dao.FindUnfinishedCycle()
+		if err != nil {
+			logger.Ctx(ctx).Error("failed to find unfinished cycle", zap.Error(err))
+			retry++
+			continue
+		}
+		if cycle == nil {
+			cycle, err = nextCycle(ctx, cfg, c)
+			if err != nil {
+				logger.Ctx(ctx).Error("failed to schedule next cycle", zap.Error(err))
+				retry++
+				continue
+			}
+			if cycle == nil {
+				continue
+			}
+		}
+		ctx := logger.WithFields(ctx, logger.EndEpoch(cycle.EndEpoch))
+		logger.Ctx(ctx).Info("running cycle", zap.String("status", cycle.Status))
+		if err := runCycle(ctx, cfg, c, cycle); err != nil {
+			logger.Ctx(ctx).Error("cycle failed", zap.String("status", cycle.Status), zap.Error(err))
+			if err := cycle.Fail(err); err != nil {
+				logger.Ctx(ctx).Error("failed to save cycle", zap.Error(err))
+			}
+			retry++
+			continue
+		}
+		retry = 0
+	}
+	return nil
+}
+
+// nextCycle creates the cycle of the next distribution window, or sends the pending deposits and waits until the
+// window is due and returns nil
+func nextCycle(ctx context.Context, cfg *config.Config, c chain.Client) (*dao.Cycle, error) {
+	health.SetPhase(health.PhaseScheduling)
+	window, err := distribute.NextWindow(ctx, cfg, c)
+	if err != nil {
+		return nil, fmt.Errorf("get next window error: %v", err)
+	}
+	health.SetNextDistribution(time.Now().Add(window.Wait(cfg)))
+
+	if !window.Due(cfg) {
+		health.SetPhase(health.PhaseSending)
+		distribute.NewSender(cfg, c).Send(ctx)
+
+		window, err = distribute.NextWindow(ctx, cfg, c)
+		if err != nil {
+			return nil, fmt.Errorf("get next window error: %v", err)
+		}
+		if wait := window.Wait(cfg); wait > 0 {
+			health.SetPhase(health.PhaseWaiting)
+			health.SetNextDistribution(time.Now().Add(wait))
+			logger.Ctx(ctx).Info("waiting for next distribution", zap.Duration("wait", wait),
+				zap.Uint64("startEpoch", window.StartEpoch), logger.EndEpoch(window.EndEpoch))
+			select {
+			case <-ctx.Done():
+			case <-time.After(wait):
+			}
+			return nil, nil
+		}
+	}
+
+	windows, err := distribute.OwedWindows(ctx, cfg, c, 0)
+	if err != nil {
+		return nil, fmt.Errorf("get owed windows error: %v", err)
+	}
+	if len(windows) > 1 {
+		logger.Ctx(ctx).Info("catching up owed windows", zap.Int("windows", len(windows)),
+			zap.Uint64("startEpoch", windows[0].StartEpoch), logger.EndEpoch(windows[len(windows)-1].EndEpoch))
+	}
+	if len(windows) == 0 {
+		return nil, nil
+	}
+	cycles, err := scheduleCycles(windows)
+	if err != nil {
+		return nil, err
+	}
+	return cycles[0], nil
+}
+
+// scheduleCycles creates the cycles of windows in order, only the first of which claims the rewards. The cycle of a
+// window which already has one is kept.
+func scheduleCycles(windows []*distribute.Window) ([]*dao.Cycle, error) {
+	cycles := make([]*dao.Cycle, 0, len(windows))
+	for i, window := range windows {
+		cycle, err := dao.FindCycleByEndEpoch(window.EndEpoch)
+		if err != nil {
+			return nil, err
+		}
+		if cycle == nil {
+			cycle = &dao.Cycle{
+				StartEpoch: window.StartEpoch,
+				EndEpoch:   window.EndEpoch,
+				Status:     dao.CycleNew,
+			}
+			if i > 0 {
+				cycle.Status = dao.CycleClaimed
+			}
+		}
+		cycles = append(cycles, cycle)
+	}
+
+	tx := dao.Transaction()
+	for _, cycle := range cycles {
+		if cycle.ID != 0 {
+			continue
+		}
+		if err := cycle.Save(tx); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return cycles, nil
+}
+
+// collectMetrics refreshes the metrics read from the chain and the database
+func collectMetrics(ctx context.Context, cfg *config.Config, c chain.Client) {
+	if meta, err := c.GetChainMeta(ctx); err == nil {
+		metrics.ChainEpoch.Set(float64(meta.Epoch.Num))
+	} else {
+		logger.Ctx(ctx).Warn("failed to get chain meta", zap.Error(err))
+	}
+	if lastEndEpoch, err := distribute.GetLastEndEpoch(ctx, cfg, c); err == nil {
+		metrics.LastEndEpoch.Set(float64(lastEndEpoch))
+	} else {
+		logger.Ctx(ctx).Warn("failed to get last end epoch", zap.Error(err))
+	}
+	if balance, err := c.GetBalance(ctx); err == nil {
+		metrics.VaultBalance.Set(metrics.IOTX(balance))
+		checkBalance(ctx, cfg, balance)
+	} else {
+		logger.Ctx(ctx).Warn("failed to get vault balance", zap.Error(err))
+	}
+	if unclaimed, err := claim.GetUnclaimedBalance(ctx, c); err == nil {
+		metrics.UnclaimedBalance.Set(metrics.IOTX(unclaimed))
+	} else {
+		logger.Ctx(ctx).Warn("failed to get unclaimed balance", zap.Error(err))
+	}
+	if counts, err := dao.CountDropRecordsByStatus(); err == nil {
+		metrics.DropRecords.Reset()
+		for status, count := range counts {
+			metrics.DropRecords.WithLabelValues(status).Set(float64(count))
+		}
+	} else {
+		logger.Ctx(ctx).Warn("failed to count drop records", zap.Error(err))
+	}
+}
+
+// lowBalanceAlerted is whether the vault balance has been alerted to be low, so the alert is only sent once until the
+// balance recovers
+var lowBalanceAlerted bool
+
+// checkBalance alerts when the vault balance falls below the configured threshold
+func checkBalance(ctx context.Context, cfg *config.Config, balance *big.Int) {
+	if !cfg.Alerts.LowBalance.IsSet() {
+		return
+	}
+	threshold := cfg.Alerts.LowBalance.Int()
+	if balance.Cmp(threshold) >= 0 {
+		lowBalanceAlerted = false
+		return
+	}
+	if lowBalanceAlerted {
+		return
+	}
+	lowBalanceAlerted = true
+	notify.Send(ctx, &notify.Event{
+		Type:    notify.EventLowBalance,
+		Message: fmt.Sprintf("vault balance %s is below %s", balance, threshold),
+		Fields:  map[string]string{"address": cfg.Vault.Address},
+	})
+}
+
+// runCycle drives cycle from its persisted status until its deposits are sent
+func runCycle(ctx context.Context, cfg *config.Config, c chain.Client, cycle *dao.Cycle) error {
+	// the commit may have landed on chain without the cycle being updated
+	lastEndEpoch, err := distribute.GetLastEndEpoch(ctx, cfg, c)
+	if err != nil {
+		return err
+	}
+	if lastEndEpoch >= cycle.EndEpoch && cycle.Status != dao.CycleCommitted && cycle.Status != dao.CycleDepositsSent {
+		if err := cycle.Advance(dao.CycleCommitted); err != nil {
+			return err
+		}
+	}
+
+	for cycle.Status != dao.CycleDepositsSent {
+		start := time.Now()
+		switch cycle.Status {
+		case dao.CycleNew:
+			health.SetPhase(health.PhaseClaiming)
+			if err := claim.Reward(ctx, cfg, c); err != nil {
+				return fmt.Errorf("claim reward error: %v", err)
+			}
+			metrics.ObservePhase("claim", start)
+			if err := cycle.Advance(dao.CycleClaimed); err != nil {
+				return err
+			}
+		case dao.CycleClaimed, dao.CycleBookkeepingFetched, dao.CycleDistributing:
+			health.SetPhase(health.PhaseDistributing)
+			if err := distribute.Reward(ctx, cfg, c, cycle); err != nil {
+				return fmt.Errorf("distribute reward error: %v", err)
+			}
+			metrics.ObservePhase("distribute", start)
+		case dao.CycleCommitted:
+			health.SetPhase(health.PhaseSending)
+			if err := distribute.SweepFees(ctx, cfg, c, cycle.EndEpoch); err != nil {
+				return fmt.Errorf("sweep fees error: %v", err)
+			}
+			distribute.NewSender(cfg, c).Send(ctx)
+			if _, err := distribute.SaveDistributionReport(ctx, cfg, c, cycle.EndEpoch); err != nil {
+				logger.Ctx(ctx).Error("failed to save distribution report", zap.Error(err))
+			}
+			metrics.ObservePhase("send", start)
+			if err := cycle.Advance(dao.CycleDepositsSent); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown cycle status %s", cycle.Status)
+		}
+	}
+	return nil
+}
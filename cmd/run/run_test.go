@@ -0,0 +1,65 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package run
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"math/big"
+	"testing"
+
+	"github.com/iotexproject/iotex-antenna-go/v2/account"
+	"github.com/jinzhu/gorm"
+	// sqlite dialects
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/cmd/distribute"
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+func TestRunOwedWindowsOfBookkeepingFile(t *testing.T) {
+	require := require.New(t)
+	gdb, err := gorm.Open("sqlite3", ":memory:")
+	require.NoError(err)
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(err)
+	require.NoError(dao.SetDatabase(gdb, priv, &priv.PublicKey))
+
+	acc, err := account.NewAccount()
+	require.NoError(err)
+	hermes, err := account.NewAccount()
+	require.NoError(err)
+	c := chain.NewFakeClient(acc)
+	// windows 1 to 24 and 25 to 48 are owed
+	c.SetChainMeta(1000, 60)
+	cfg := &config.Config{}
+	*cfg = config.Default
+	cfg.Contracts.Hermes = hermes.Address().String()
+	c.HandleRead(cfg.Contracts.Hermes, "getEndEpochCount", func(args []interface{}) ([]interface{}, error) {
+		return []interface{}{big.NewInt(0)}, nil
+	})
+	ctx := context.Background()
+
+	// the daemon refuses to schedule a cycle per owed window, as the bookkeeping file only holds one of them
+	cfg.Bookkeeping.Source = distribute.BookkeepingSourceFile
+	require.Error(Run(ctx, cfg, c))
+	cycles, err := dao.FindCyclesByLimit(10)
+	require.NoError(err)
+	require.Empty(cycles)
+
+	cfg.Bookkeeping.Source = distribute.BookkeepingSourceGraphQL
+	cycle, err := nextCycle(ctx, cfg, c)
+	require.NoError(err)
+	require.Equal(uint64(24), cycle.EndEpoch)
+	cycles, err = dao.FindCyclesByLimit(10)
+	require.NoError(err)
+	require.Len(cycles, 2)
+}
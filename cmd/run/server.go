@@ -0,0 +1,51 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package run
+
+import (
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/chain"
+	"github.com/iotexproject/iotex-hermes/cmd/dao"
+	"github.com/iotexproject/iotex-hermes/cmd/distribute"
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/health"
+	"github.com/iotexproject/iotex-hermes/logger"
+	"github.com/iotexproject/iotex-hermes/metrics"
+)
+
+// serve serves the metrics and the health checks of the daemon on cfg.Metrics.Listen in the background
+func serve(cfg *config.Config, c chain.Client) (*http.Server, error) {
+	source, err := distribute.NewBookkeepingSource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	health.Configure(cfg.Health.StuckAfter, cfg.Health.CheckTimeout)
+	health.AddCheck("chain", func(ctx context.Context) error {
+		_, err := c.GetChainMeta(ctx)
+		return err
+	})
+	health.AddCheck("database", func(ctx context.Context) error {
+		return dao.DB().DB().PingContext(ctx)
+	})
+	health.AddCheck("bookkeeping", source.Ping)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", health.HandleLive)
+	mux.HandleFunc("/readyz", health.HandleReady)
+	server := &http.Server{Addr: cfg.Metrics.Listen, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.L().Error("http server failed", zap.Error(err))
+		}
+	}()
+	return server, nil
+}
@@ -0,0 +1,550 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iotexproject/iotex-address/address"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// File is the path of the config file, set by the --config flag
+var File string
+
+type (
+	// Config defines the configuration of hermes
+	Config struct {
+		Endpoint          string       `yaml:"endpoint" env:"IO_ENDPOINT"`
+		AnalyticsEndpoint string       `yaml:"analyticsEndpoint" env:"ANALYTICS_ENDPOINT"`
+		Vault             Vault        `yaml:"vault"`
+		Database          Database     `yaml:"database"`
+		Contracts         Contracts    `yaml:"contracts"`
+		Gas               Gas          `yaml:"gas"`
+		Distribution      Distribution `yaml:"distribution"`
+		Receipt           Receipt      `yaml:"receipt"`
+		Bookkeeping       Bookkeeping  `yaml:"bookkeeping"`
+		Schedule          Schedule     `yaml:"schedule"`
+		Metrics           Metrics      `yaml:"metrics"`
+		Health            Health       `yaml:"health"`
+		Alerts            Alerts       `yaml:"alerts"`
+		Reports           Reports      `yaml:"reports"`
+		Log               Log          `yaml:"log"`
+	}
+
+	// Vault defines the distributor account
+	Vault struct {
+		Password string `yaml:"password" env:"VAULT_PASSWORD"`
+		Address  string `yaml:"address" env:"VAULT_ADDRESS"`
+	}
+
+	// Database defines the database connection and the keys signing the drop records
+	Database struct {
+		Conn       string `yaml:"conn" env:"DB_CONN"`
+		RSAPrivate string `yaml:"rsaPrivate" env:"RSA_PRIVATE"`
+		RSAPublic  string `yaml:"rsaPublic" env:"RSA_PUBLIC"`
+	}
+
+	// Contracts defines the addresses of the contracts hermes calls. ForwardRegistration is read from the Hermes
+	// contract if it is not set.
+	Contracts struct {
+		Hermes              string `yaml:"hermes" env:"HERMES_CONTRACT_ADDRESS"`
+		Multisend           string `yaml:"multisend" env:"MULTISEND_CONTRACT_ADDRESS"`
+		AutoDeposit         string `yaml:"autoDeposit" env:"AUTO_DEPOSIT_CONTRACT_ADDRESS"`
+		ForwardRegistration string `yaml:"forwardRegistration" env:"FORWARD_REGISTRATION_CONTRACT_ADDRESS"`
+	}
+
+	// Gas defines the gas of the actions hermes sends. A price, or limit of contract executions, which is not set is
+	// suggested or estimated by the node for every action, the estimated limit raised by the multiplier, and both
+	// capped by the maximums.
+	Gas struct {
+		Price      BigInt  `yaml:"price" env:"GAS_PRICE"`
+		Limit      uint64  `yaml:"limit" env:"GAS_LIMIT"`
+		Multiplier float64 `yaml:"multiplier" env:"GAS_MULTIPLIER"`
+		MaxPrice   BigInt  `yaml:"maxPrice" env:"GAS_MAX_PRICE"`
+		MaxLimit   uint64  `yaml:"maxLimit" env:"GAS_MAX_LIMIT"`
+	}
+
+	// Distribution defines how rewards are distributed. The service fee of a delegate is charged by its policy in
+	// FeePolicies, else by FeePolicy, else by BaseCharge + ChargePerRecipient * voter count. The fees of a committed
+	// window are swept to FeeTreasury, unless it is empty.
+	Distribution struct {
+		ChunkSize          int                  `yaml:"chunkSize" env:"CHUNK_SIZE"`
+		WaiverThreshold    int                  `yaml:"waiverThreshold" env:"WAIVER_THRESHOLD"`
+		BaseCharge         BigInt               `yaml:"baseCharge" env:"BASE_CHARGE"`
+		ChargePerRecipient BigInt               `yaml:"chargePerRecipient" env:"CHARGE_PER_RECIPIENT"`
+		FeePolicy          FeePolicy            `yaml:"feePolicy"`
+		FeePolicies        map[string]FeePolicy `yaml:"feePolicies"`
+		FeeTreasury        string               `yaml:"feeTreasury" env:"FEE_TREASURY_ADDRESS"`
+	}
+
+	// FeePolicy defines a service fee policy of Type flat (Amount), linear (Base + PerRecipient * voter count),
+	// percentage (BasisPoints of the reward of the delegate), tiered (the Fee of the last of Tiers whose MinRecipients
+	// the voter count reaches) or capped (Policy capped by Max)
+	FeePolicy struct {
+		Type         string     `yaml:"type"`
+		Amount       BigInt     `yaml:"amount"`
+		Base         BigInt     `yaml:"base"`
+		PerRecipient BigInt     `yaml:"perRecipient"`
+		BasisPoints  uint64     `yaml:"basisPoints"`
+		Tiers        []FeeTier  `yaml:"tiers"`
+		Max          BigInt     `yaml:"max"`
+		Policy       *FeePolicy `yaml:"policy"`
+	}
+
+	// FeeTier defines the fee of the delegates with at least MinRecipients voters
+	FeeTier struct {
+		MinRecipients int64  `yaml:"minRecipients"`
+		Fee           BigInt `yaml:"fee"`
+	}
+
+	// Receipt defines how the receipt of a sent action is polled
+	Receipt struct {
+		Timeout     time.Duration `yaml:"timeout" env:"RECEIPT_TIMEOUT"`
+		Interval    time.Duration `yaml:"interval" env:"RECEIPT_INTERVAL"`
+		MaxInterval time.Duration `yaml:"maxInterval" env:"RECEIPT_MAX_INTERVAL"`
+		Backoff     float64       `yaml:"backoff" env:"RECEIPT_BACKOFF"`
+	}
+
+	// Bookkeeping defines where the rewards of the voters are read from, either the analytics GraphQL endpoint or a
+	// JSON or CSV file signed by the RSA key PublicKey
+	Bookkeeping struct {
+		Source    string `yaml:"source" env:"BOOKKEEPING_SOURCE"`
+		File      string `yaml:"file" env:"BOOKKEEPING_FILE"`
+		PublicKey string `yaml:"publicKey" env:"BOOKKEEPING_PUBLIC_KEY"`
+	}
+
+	// Schedule defines the distribution cadence. A window of WindowEpochs epochs is distributed once its end epoch is
+	// FinalityLag epochs old, and a delegate in Delegates is only distributed once its own window has passed.
+	Schedule struct {
+		WindowEpochs  uint64                      `yaml:"windowEpochs" env:"WINDOW_EPOCHS"`
+		FinalityLag   uint64                      `yaml:"finalityLag" env:"FINALITY_LAG"`
+		EpochDuration time.Duration               `yaml:"epochDuration" env:"EPOCH_DURATION"`
+		Delegates     map[string]DelegateSchedule `yaml:"delegates"`
+	}
+
+	// Metrics defines the address the run command serves the Prometheus metrics and the health checks on, disabled if
+	// Listen is empty
+	Metrics struct {
+		Listen string `yaml:"listen" env:"METRICS_LISTEN"`
+	}
+
+	// Health defines how long the daemon may stay in a phase before it is reported stuck, and the timeout of the
+	// readiness checks
+	Health struct {
+		StuckAfter   time.Duration `yaml:"stuckAfter" env:"HEALTH_STUCK_AFTER"`
+		CheckTimeout time.Duration `yaml:"checkTimeout" env:"HEALTH_CHECK_TIMEOUT"`
+	}
+
+	// Alerts defines where the failures of a distribution are posted, and the vault balance in Rau below which an alert
+	// is raised, disabled if it is not set
+	Alerts struct {
+		Webhooks      []string      `yaml:"webhooks"`
+		SlackWebhooks []string      `yaml:"slackWebhooks"`
+		Email         Email         `yaml:"email"`
+		LowBalance    BigInt        `yaml:"lowBalance" env:"ALERT_LOW_BALANCE"`
+		Timeout       time.Duration `yaml:"timeout" env:"ALERT_TIMEOUT"`
+	}
+
+	// Reports defines the directory the signed distribution reports of the committed windows are written to, besides
+	// the database, disabled if empty
+	Reports struct {
+		Dir string `yaml:"dir" env:"REPORTS_DIR"`
+	}
+
+	// Email defines the SMTP server mailing the alerts to the comma separated addresses To
+	Email struct {
+		SMTPAddress string `yaml:"smtpAddress" env:"ALERT_SMTP_ADDRESS"`
+		Username    string `yaml:"username" env:"ALERT_SMTP_USERNAME"`
+		Password    string `yaml:"password" env:"ALERT_SMTP_PASSWORD"`
+		From        string `yaml:"from" env:"ALERT_EMAIL_FROM"`
+		To          string `yaml:"to" env:"ALERT_EMAIL_TO"`
+	}
+
+	// Log defines the level, one of debug, info, warn and error, and the format, console or json, of the logs
+	Log struct {
+		Level  string `yaml:"level" env:"LOG_LEVEL"`
+		Format string `yaml:"format" env:"LOG_FORMAT"`
+	}
+
+	// DelegateSchedule defines the cadence of a delegate
+	DelegateSchedule struct {
+		WindowEpochs uint64 `yaml:"windowEpochs"`
+	}
+)
+
+// Default is the default config, which the config file and the environment variables override
+var Default = Config{
+	Gas: Gas{
+		Multiplier: 1.2,
+		MaxLimit:   7000000,
+	},
+	Receipt: Receipt{
+		Timeout:     time.Minute,
+		Interval:    time.Second,
+		MaxInterval: 10 * time.Second,
+		Backoff:     2,
+	},
+	Bookkeeping: Bookkeeping{
+		Source: "graphql",
+	},
+	Schedule: Schedule{
+		WindowEpochs:  24,
+		FinalityLag:   2,
+		EpochDuration: time.Hour,
+	},
+	Health: Health{
+		StuckAfter:   2 * time.Hour,
+		CheckTimeout: 5 * time.Second,
+	},
+	Alerts: Alerts{
+		Timeout: 10 * time.Second,
+	},
+	Log: Log{
+		Level:  "info",
+		Format: "console",
+	},
+}
+
+// validate returns the problems of the policy named name
+func (p *FeePolicy) validate(name string) []string {
+	var problems []string
+	bigInt := func(field string, value BigInt) {
+		if !value.IsSet() {
+			problems = append(problems, fmt.Sprintf("%s.%s is not defined", name, field))
+		} else if value.Int().Sign() < 0 {
+			problems = append(problems, fmt.Sprintf("%s.%s must not be negative", name, field))
+		}
+	}
+	switch p.Type {
+	case "flat":
+		bigInt("amount", p.Amount)
+	case "linear":
+		bigInt("base", p.Base)
+		bigInt("perRecipient", p.PerRecipient)
+	case "percentage":
+		if p.BasisPoints > 10000 {
+			problems = append(problems, fmt.Sprintf("%s.basisPoints must not be greater than 10000", name))
+		}
+	case "tiered":
+		if len(p.Tiers) == 0 {
+			problems = append(problems, fmt.Sprintf("%s.tiers is not defined", name))
+		}
+		for i, tier := range p.Tiers {
+			if i > 0 && tier.MinRecipients <= p.Tiers[i-1].MinRecipients {
+				problems = append(problems, fmt.Sprintf("%s.tiers must be in increasing order of minRecipients", name))
+			}
+			bigInt(fmt.Sprintf("tiers[%d].fee", i), tier.Fee)
+		}
+	case "capped":
+		bigInt("max", p.Max)
+		if p.Policy == nil {
+			problems = append(problems, fmt.Sprintf("%s.policy is not defined", name))
+		} else {
+			problems = append(problems, p.Policy.validate(name+".policy")...)
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("%s.type %s is not one of flat, linear, percentage, tiered and capped", name, p.Type))
+	}
+	return problems
+}
+
+// BigInt is a big integer which is written as a decimal string in config
+type BigInt struct {
+	v *big.Int
+}
+
+// NewBigInt returns a BigInt of v
+func NewBigInt(v *big.Int) BigInt {
+	return BigInt{v: new(big.Int).Set(v)}
+}
+
+// Set parses the decimal string s
+func (b *BigInt) Set(s string) error {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return errors.Errorf("failed to convert %s to big int", s)
+	}
+	b.v = v
+	return nil
+}
+
+// IsSet returns whether the value is set
+func (b BigInt) IsSet() bool {
+	return b.v != nil
+}
+
+// Int returns a copy of the value, 0 if it is not set
+func (b BigInt) Int() *big.Int {
+	if b.v == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Set(b.v)
+}
+
+// String returns the decimal string of the value
+func (b BigInt) String() string {
+	return b.Int().String()
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, leaving the value unset if the string is empty
+func (b *BigInt) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	if s == "" {
+		b.v = nil
+		return nil
+	}
+	return b.Set(s)
+}
+
+// MarshalYAML implements yaml.Marshaler
+func (b BigInt) MarshalYAML() (interface{}, error) {
+	return b.String(), nil
+}
+
+// Load reads the config file at path if it is not empty, applies the environment variable overrides and validates the
+// result
+func Load(path string) (*Config, error) {
+	cfg, problems, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	return checked(cfg, append(problems, cfg.validate()...))
+}
+
+// LoadAnalytics reads the config like Load, but only validates the analytics endpoint, the vault address and the
+// waiver threshold, which is all a bookkeeping export reads
+func LoadAnalytics(path string) (*Config, error) {
+	cfg, problems, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AnalyticsEndpoint == "" {
+		problems = append(problems, "analyticsEndpoint is not defined")
+	}
+	if cfg.Vault.Address == "" {
+		problems = append(problems, "vault.address is not defined")
+	} else if _, err := address.FromString(cfg.Vault.Address); err != nil {
+		problems = append(problems, fmt.Sprintf("vault.address is not a valid address: %v", err))
+	}
+	if cfg.Distribution.WaiverThreshold < 0 {
+		problems = append(problems, "distribution.waiverThreshold must not be negative")
+	}
+	return checked(cfg, problems)
+}
+
+// read reads the config file at path if it is not empty and applies the environment variable overrides, returning the
+// problems of the overrides
+func read(path string) (*Config, []string, error) {
+	cfg := &Config{}
+	*cfg = Default
+	if path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read config file")
+		}
+		if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to parse config file")
+		}
+	}
+	var problems []string
+	applyEnv(reflect.ValueOf(cfg).Elem(), &problems)
+	return cfg, problems, nil
+}
+
+// checked returns cfg, or an error listing all the problems if there is any
+func checked(cfg *Config, problems []string) (*Config, error) {
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid config:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides the fields of v with the environment variables named by their env tags
+func applyEnv(v reflect.Value, problems *[]string) {
+	bigIntType := reflect.TypeOf(BigInt{})
+	durationType := reflect.TypeOf(time.Duration(0))
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && field.Type() != bigIntType {
+			applyEnv(field, problems)
+			continue
+		}
+		key := v.Type().Field(i).Tag.Get("env")
+		if key == "" {
+			continue
+		}
+		str, ok := os.LookupEnv(key)
+		if !ok || str == "" {
+			continue
+		}
+		var err error
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(str)
+		case reflect.Int, reflect.Int64:
+			if field.Type() == durationType {
+				var d time.Duration
+				if d, err = time.ParseDuration(str); err == nil {
+					field.SetInt(int64(d))
+				}
+				break
+			}
+			var n int64
+			if n, err = strconv.ParseInt(str, 10, 64); err == nil {
+				field.SetInt(n)
+			}
+		case reflect.Uint64:
+			var n uint64
+			if n, err = strconv.ParseUint(str, 10, 64); err == nil {
+				field.SetUint(n)
+			}
+		case reflect.Float64:
+			var f float64
+			if f, err = strconv.ParseFloat(str, 64); err == nil {
+				field.SetFloat(f)
+			}
+		case reflect.Bool:
+			var b bool
+			if b, err = strconv.ParseBool(str); err == nil {
+				field.SetBool(b)
+			}
+		case reflect.Struct:
+			err = field.Addr().Interface().(*BigInt).Set(str)
+		default:
+			err = errors.Errorf("unsupported type %s", field.Type())
+		}
+		if err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+}
+
+func (cfg *Config) validate() []string {
+	var problems []string
+	required := func(name, value string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s is not defined", name))
+		}
+	}
+	ioAddress := func(name, value string) {
+		if value == "" {
+			problems = append(problems, fmt.Sprintf("%s is not defined", name))
+			return
+		}
+		if _, err := address.FromString(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not a valid address: %v", name, err))
+		}
+	}
+	positive := func(name string, value int64) {
+		if value <= 0 {
+			problems = append(problems, fmt.Sprintf("%s must be positive", name))
+		}
+	}
+	bigInt := func(name string, value BigInt) {
+		if !value.IsSet() {
+			problems = append(problems, fmt.Sprintf("%s is not defined", name))
+		} else if value.Int().Sign() < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", name))
+		}
+	}
+
+	required("endpoint", cfg.Endpoint)
+	required("vault.password", cfg.Vault.Password)
+	ioAddress("vault.address", cfg.Vault.Address)
+	required("database.conn", cfg.Database.Conn)
+	required("database.rsaPrivate", cfg.Database.RSAPrivate)
+	required("database.rsaPublic", cfg.Database.RSAPublic)
+	ioAddress("contracts.hermes", cfg.Contracts.Hermes)
+	ioAddress("contracts.multisend", cfg.Contracts.Multisend)
+	ioAddress("contracts.autoDeposit", cfg.Contracts.AutoDeposit)
+	if cfg.Contracts.ForwardRegistration != "" {
+		ioAddress("contracts.forwardRegistration", cfg.Contracts.ForwardRegistration)
+	}
+	if cfg.Gas.Price.IsSet() {
+		bigInt("gas.price", cfg.Gas.Price)
+	}
+	if cfg.Gas.MaxPrice.IsSet() {
+		bigInt("gas.maxPrice", cfg.Gas.MaxPrice)
+	}
+	if cfg.Gas.Multiplier < 1 {
+		problems = append(problems, "gas.multiplier must not be less than 1")
+	}
+	if cfg.Gas.Limit == 0 {
+		positive("gas.maxLimit", int64(cfg.Gas.MaxLimit))
+	}
+	positive("distribution.chunkSize", int64(cfg.Distribution.ChunkSize))
+	if cfg.Distribution.WaiverThreshold < 0 {
+		problems = append(problems, "distribution.waiverThreshold must not be negative")
+	}
+	bigInt("distribution.baseCharge", cfg.Distribution.BaseCharge)
+	bigInt("distribution.chargePerRecipient", cfg.Distribution.ChargePerRecipient)
+	if cfg.Distribution.FeePolicy.Type != "" {
+		problems = append(problems, cfg.Distribution.FeePolicy.validate("distribution.feePolicy")...)
+	}
+	for name, policy := range cfg.Distribution.FeePolicies {
+		problems = append(problems, policy.validate("distribution.feePolicies."+name)...)
+	}
+	if cfg.Distribution.FeeTreasury != "" {
+		ioAddress("distribution.feeTreasury", cfg.Distribution.FeeTreasury)
+	}
+	positive("receipt.timeout", int64(cfg.Receipt.Timeout))
+	positive("receipt.interval", int64(cfg.Receipt.Interval))
+	if cfg.Receipt.MaxInterval < cfg.Receipt.Interval {
+		problems = append(problems, "receipt.maxInterval must not be less than receipt.interval")
+	}
+	if cfg.Receipt.Backoff < 1 {
+		problems = append(problems, "receipt.backoff must not be less than 1")
+	}
+	positive("schedule.windowEpochs", int64(cfg.Schedule.WindowEpochs))
+	positive("schedule.epochDuration", int64(cfg.Schedule.EpochDuration))
+	for name, delegate := range cfg.Schedule.Delegates {
+		if delegate.WindowEpochs < cfg.Schedule.WindowEpochs {
+			problems = append(problems, fmt.Sprintf("schedule.delegates.%s.windowEpochs must not be less than schedule.windowEpochs", name))
+		}
+	}
+	positive("health.stuckAfter", int64(cfg.Health.StuckAfter))
+	positive("health.checkTimeout", int64(cfg.Health.CheckTimeout))
+	positive("alerts.timeout", int64(cfg.Alerts.Timeout))
+	if cfg.Alerts.Email.SMTPAddress != "" {
+		required("alerts.email.from", cfg.Alerts.Email.From)
+		required("alerts.email.to", cfg.Alerts.Email.To)
+	}
+	switch cfg.Log.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log.level %s is not one of debug, info, warn and error", cfg.Log.Level))
+	}
+	if cfg.Log.Format != "console" && cfg.Log.Format != "json" {
+		problems = append(problems, fmt.Sprintf("log.format %s is neither console nor json", cfg.Log.Format))
+	}
+	switch cfg.Bookkeeping.Source {
+	case "graphql":
+		required("analyticsEndpoint", cfg.AnalyticsEndpoint)
+	case "file":
+		required("bookkeeping.file", cfg.Bookkeeping.File)
+		required("bookkeeping.publicKey", cfg.Bookkeeping.PublicKey)
+		if len(cfg.Schedule.Delegates) > 0 {
+			problems = append(problems, "schedule.delegates is not supported by bookkeeping.source file, whose file "+
+				"only holds the epochs of one window")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("bookkeeping.source %s is neither graphql nor file", cfg.Bookkeeping.Source))
+	}
+	return problems
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAddress = "io1lvemm43lz6np0hzcqlpk0kpxxww623z5hs4mwu"
+	testConfig  = `
+endpoint: api.testnet.iotex.one:443
+analyticsEndpoint: http://127.0.0.1:8089/query
+vault:
+  password: password
+  address: io1lvemm43lz6np0hzcqlpk0kpxxww623z5hs4mwu
+database:
+  conn: conn
+  rsaPrivate: private
+  rsaPublic: public
+contracts:
+  hermes: io1lvemm43lz6np0hzcqlpk0kpxxww623z5hs4mwu
+  multisend: io1lvemm43lz6np0hzcqlpk0kpxxww623z5hs4mwu
+  autoDeposit: io1lvemm43lz6np0hzcqlpk0kpxxww623z5hs4mwu
+gas:
+  price: "1000000000000"
+  limit: 7000000
+  maxPrice: ""
+distribution:
+  chunkSize: 300
+  waiverThreshold: 100
+  baseCharge: "10"
+  chargePerRecipient: "1"
+receipt:
+  timeout: 2m
+schedule:
+  delegates:
+    weekly:
+      windowEpochs: 168
+`
+)
+
+func writeConfig(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "hermes-config")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	require := require.New(t)
+
+	path := writeConfig(t, testConfig)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	cfg, err := Load(path)
+	require.NoError(err)
+	require.Equal("api.testnet.iotex.one:443", cfg.Endpoint)
+	require.Equal(testAddress, cfg.Contracts.Hermes)
+	require.Equal("1000000000000", cfg.Gas.Price.String())
+	require.Equal(uint64(7000000), cfg.Gas.Limit)
+	require.Equal(Default.Gas.Multiplier, cfg.Gas.Multiplier)
+	require.False(cfg.Gas.MaxPrice.IsSet())
+	require.Equal(300, cfg.Distribution.ChunkSize)
+	require.Equal("10", cfg.Distribution.BaseCharge.String())
+	require.Equal(2*time.Minute, cfg.Receipt.Timeout)
+	require.Equal(Default.Receipt.Interval, cfg.Receipt.Interval)
+	require.Equal(uint64(24), cfg.Schedule.WindowEpochs)
+	require.Equal(uint64(168), cfg.Schedule.Delegates["weekly"].WindowEpochs)
+
+	os.Setenv("CHUNK_SIZE", "100")
+	os.Setenv("GAS_PRICE", "2000000000000")
+	os.Setenv("RECEIPT_TIMEOUT", "30s")
+	defer os.Unsetenv("CHUNK_SIZE")
+	defer os.Unsetenv("RECEIPT_TIMEOUT")
+	defer os.Unsetenv("GAS_PRICE")
+	cfg, err = Load(path)
+	require.NoError(err)
+	require.Equal(100, cfg.Distribution.ChunkSize)
+	require.Equal("2000000000000", cfg.Gas.Price.String())
+	require.Equal(30*time.Second, cfg.Receipt.Timeout)
+
+	// the bookkeeping file holds one window, which the delegate schedules reach past
+	os.Setenv("BOOKKEEPING_SOURCE", "file")
+	os.Setenv("BOOKKEEPING_FILE", "bookkeeping.json")
+	os.Setenv("BOOKKEEPING_PUBLIC_KEY", "public")
+	defer os.Unsetenv("BOOKKEEPING_SOURCE")
+	defer os.Unsetenv("BOOKKEEPING_FILE")
+	defer os.Unsetenv("BOOKKEEPING_PUBLIC_KEY")
+	_, err = Load(path)
+	require.Error(err)
+	require.Contains(err.Error(), "schedule.delegates is not supported by bookkeeping.source file")
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	require := require.New(t)
+
+	path := writeConfig(t, "endpoint: api.testnet.iotex.one:443\ncontracts:\n  hermes: invalid\n"+
+		"schedule:\n  delegates:\n    daily:\n      windowEpochs: 12\n"+
+		"distribution:\n  feePolicies:\n    alpha:\n      type: capped\n      policy:\n        type: percentage\n"+
+		"        basisPoints: 20000\n    beta:\n      type: tiered\n      tiers:\n        - {minRecipients: 10, fee: \"1\"}\n"+
+		"        - {minRecipients: 5, fee: \"2\"}\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	os.Setenv("GAS_LIMIT", "abc")
+	os.Setenv("RECEIPT_BACKOFF", "0.5")
+	os.Setenv("GAS_MULTIPLIER", "0.8")
+	os.Setenv("LOG_FORMAT", "xml")
+	defer os.Unsetenv("GAS_LIMIT")
+	defer os.Unsetenv("RECEIPT_BACKOFF")
+	defer os.Unsetenv("GAS_MULTIPLIER")
+	defer os.Unsetenv("LOG_FORMAT")
+	_, err := Load(path)
+	require.Error(err)
+	for _, problem := range []string{
+		"GAS_LIMIT",
+		"analyticsEndpoint is not defined",
+		"vault.password is not defined",
+		"contracts.hermes is not a valid address",
+		"distribution.chunkSize must be positive",
+		"receipt.backoff must not be less than 1",
+		"gas.multiplier must not be less than 1",
+		"schedule.delegates.daily.windowEpochs must not be less than schedule.windowEpochs",
+		"log.format xml is neither console nor json",
+		"distribution.feePolicies.alpha.max is not defined",
+		"distribution.feePolicies.alpha.policy.basisPoints must not be greater than 10000",
+		"distribution.feePolicies.beta.tiers must be in increasing order of minRecipients",
+	} {
+		require.Contains(err.Error(), problem)
+	}
+}
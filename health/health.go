@@ -0,0 +1,181 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package health reports the phase of the distribution daemon and the reachability of its dependencies. The liveness
+// check fails if the daemon stays in a phase for too long, and the readiness check fails if a dependency is down.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Phases of the daemon loop
+const (
+	PhaseStarting     = "starting"
+	PhaseScheduling   = "scheduling"
+	PhaseWaiting      = "waiting"
+	PhaseClaiming     = "claiming"
+	PhaseDistributing = "distributing"
+	PhaseSending      = "sending"
+)
+
+// Check returns an error if a dependency is not reachable
+type Check func(ctx context.Context) error
+
+type (
+	// Liveness is the response of the liveness check
+	Liveness struct {
+		Status           string     `json:"status"`
+		Phase            string     `json:"phase"`
+		PhaseSince       time.Time  `json:"phaseSince"`
+		NextDistribution *time.Time `json:"nextDistribution,omitempty"`
+	}
+
+	// Readiness is the response of the readiness check, with the error of every failed check
+	Readiness struct {
+		Ready            bool              `json:"ready"`
+		Phase            string            `json:"phase"`
+		NextDistribution *time.Time        `json:"nextDistribution,omitempty"`
+		Checks           map[string]string `json:"checks"`
+	}
+)
+
+var (
+	mu               sync.Mutex
+	phase            = PhaseStarting
+	phaseSince       = time.Now()
+	nextDistribution time.Time
+	checks           = make(map[string]Check)
+	stuckAfter       = 2 * time.Hour
+	checkTimeout     = 5 * time.Second
+)
+
+// Configure sets how long the daemon may stay in a phase other than waiting, and the timeout of every check
+func Configure(stuck time.Duration, timeout time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	stuckAfter = stuck
+	checkTimeout = timeout
+}
+
+// SetPhase records the phase the daemon enters
+func SetPhase(p string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if p != phase {
+		phase = p
+		phaseSince = time.Now()
+	}
+}
+
+// SetNextDistribution records the time the next distribution window is due
+func SetNextDistribution(t time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	nextDistribution = t
+}
+
+// AddCheck registers the readiness check of the dependency name
+func AddCheck(name string, check Check) {
+	mu.Lock()
+	defer mu.Unlock()
+	checks[name] = check
+}
+
+// Live returns the liveness of the daemon at now. The daemon is stuck if it stays in a phase for longer than the
+// configured duration, or still waits that long after the next distribution is due.
+func Live(now time.Time) *Liveness {
+	mu.Lock()
+	defer mu.Unlock()
+	l := &Liveness{Status: "ok", Phase: phase, PhaseSince: phaseSince}
+	if !nextDistribution.IsZero() {
+		next := nextDistribution
+		l.NextDistribution = &next
+	}
+	switch {
+	case phase == PhaseWaiting && !nextDistribution.IsZero():
+		if now.Sub(nextDistribution) > stuckAfter {
+			l.Status = "stuck"
+		}
+	case phase != PhaseWaiting && now.Sub(phaseSince) > stuckAfter:
+		l.Status = "stuck"
+	}
+	return l
+}
+
+// Ready runs every check concurrently and returns the readiness of the daemon
+func Ready(ctx context.Context) *Readiness {
+	mu.Lock()
+	r := &Readiness{Ready: true, Phase: phase, Checks: make(map[string]string)}
+	if !nextDistribution.IsZero() {
+		next := nextDistribution
+		r.NextDistribution = &next
+	}
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	registered := make([]Check, len(names))
+	for i, name := range names {
+		registered[i] = checks[name]
+	}
+	timeout := checkTimeout
+	mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	results := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i := range registered {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = registered[i](ctx)
+		}(i)
+	}
+	wg.Wait()
+	for i, name := range names {
+		if results[i] != nil {
+			r.Ready = false
+			r.Checks[name] = results[i].Error()
+		} else {
+			r.Checks[name] = "ok"
+		}
+	}
+	return r
+}
+
+// HandleLive serves the liveness check, with status 503 if the daemon is stuck
+func HandleLive(w http.ResponseWriter, r *http.Request) {
+	l := Live(time.Now())
+	code := http.StatusOK
+	if l.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, l)
+}
+
+// HandleReady serves the readiness check, with status 503 if a dependency is not reachable
+func HandleReady(w http.ResponseWriter, r *http.Request) {
+	ready := Ready(r.Context())
+	code := http.StatusOK
+	if !ready.Ready {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, ready)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
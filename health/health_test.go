@@ -0,0 +1,68 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLive(t *testing.T) {
+	require := require.New(t)
+
+	Configure(time.Hour, time.Second)
+	SetPhase(PhaseDistributing)
+	now := time.Now()
+	require.Equal("ok", Live(now).Status)
+	require.Equal(PhaseDistributing, Live(now).Phase)
+	require.Equal("stuck", Live(now.Add(2*time.Hour)).Status)
+
+	// waiting is only stuck once the next distribution is overdue
+	SetPhase(PhaseWaiting)
+	SetNextDistribution(now.Add(10 * time.Hour))
+	require.Equal("ok", Live(now.Add(5*time.Hour)).Status)
+	require.Equal("stuck", Live(now.Add(12*time.Hour)).Status)
+	require.Equal(now.Add(10*time.Hour), *Live(now).NextDistribution)
+
+	rec := httptest.NewRecorder()
+	HandleLive(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(http.StatusOK, rec.Code)
+	l := &Liveness{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), l))
+	require.Equal(PhaseWaiting, l.Phase)
+}
+
+func TestReady(t *testing.T) {
+	require := require.New(t)
+
+	Configure(time.Hour, 50*time.Millisecond)
+	AddCheck("chain", func(ctx context.Context) error { return nil })
+	AddCheck("database", func(ctx context.Context) error { return nil })
+	r := Ready(context.Background())
+	require.True(r.Ready)
+	require.Equal(map[string]string{"chain": "ok", "database": "ok"}, r.Checks)
+
+	AddCheck("bookkeeping", func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("analytics endpoint timed out")
+	})
+	rec := httptest.NewRecorder()
+	HandleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(http.StatusServiceUnavailable, rec.Code)
+	r = &Readiness{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), r))
+	require.False(r.Ready)
+	require.Equal("analytics endpoint timed out", r.Checks["bookkeeping"])
+	require.Equal("ok", r.Checks["chain"])
+}
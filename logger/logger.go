@@ -0,0 +1,102 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package logger provides the leveled structured logger of hermes. The correlation fields of a cycle, a delegate or a
+// drop record are carried by the context, so every line logged with it can be filtered by them.
+package logger
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+// Log formats
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+type contextKey struct{}
+
+var std = newLogger(zapcore.InfoLevel, FormatConsole, zapcore.Lock(os.Stderr))
+
+// Init replaces the logger with the one of cfg
+func Init(cfg config.Log) error {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return fmt.Errorf("invalid log level %s", cfg.Level)
+	}
+	if cfg.Format != FormatConsole && cfg.Format != FormatJSON {
+		return fmt.Errorf("invalid log format %s", cfg.Format)
+	}
+	std = newLogger(level, cfg.Format, zapcore.Lock(os.Stderr))
+	return nil
+}
+
+func newLogger(level zapcore.Level, format string, out zapcore.WriteSyncer) *zap.Logger {
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	if format == FormatJSON {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	} else {
+		encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+	}
+	return zap.New(zapcore.NewCore(encoder, out, level))
+}
+
+// L returns the logger without any correlation field
+func L() *zap.Logger {
+	return std
+}
+
+// Ctx returns the logger with the correlation fields of ctx
+func Ctx(ctx context.Context) *zap.Logger {
+	if l, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return l
+	}
+	return std
+}
+
+// WithFields returns a context whose logger adds fields to the ones of ctx
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, contextKey{}, Ctx(ctx).With(fields...))
+}
+
+// EndEpoch is the end epoch of the distribution window
+func EndEpoch(endEpoch uint64) zap.Field {
+	return zap.Uint64("endEpoch", endEpoch)
+}
+
+// Delegate is the name of the delegate being distributed
+func Delegate(name string) zap.Field {
+	return zap.String("delegate", name)
+}
+
+// ActionHash is the hash of a sent action
+func ActionHash(h hash.Hash256) zap.Field {
+	return zap.String("actionHash", hex.EncodeToString(h[:]))
+}
+
+// RecordID is the ID of a drop record
+func RecordID(id uint) zap.Field {
+	return zap.Uint("recordID", id)
+}
+
+// BucketID is the index of the auto deposit bucket of a voter
+func BucketID(id uint64) zap.Field {
+	return zap.Uint64("bucketID", id)
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/iotexproject/go-pkgs/hash"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+func TestContextFields(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	defer func(l *zap.Logger) { std = l }(std)
+	std = newLogger(zapcore.InfoLevel, FormatJSON, zapcore.AddSync(&buf))
+
+	ctx := WithFields(context.Background(), EndEpoch(48))
+	Ctx(ctx).Debug("hidden")
+	ctx = WithFields(ctx, Delegate("alpha"), RecordID(7), BucketID(12))
+	Ctx(ctx).Info("sent", ActionHash(hash.ZeroHash256))
+	L().Warn("plain")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(lines, 2)
+	var entry map[string]interface{}
+	require.NoError(json.Unmarshal([]byte(lines[0]), &entry))
+	require.Equal("info", entry["level"])
+	require.Equal("sent", entry["msg"])
+	require.Equal(float64(48), entry["endEpoch"])
+	require.Equal("alpha", entry["delegate"])
+	require.Equal(float64(7), entry["recordID"])
+	require.Equal(float64(12), entry["bucketID"])
+	require.Equal(strings.Repeat("0", 64), entry["actionHash"])
+	entry = nil
+	require.NoError(json.Unmarshal([]byte(lines[1]), &entry))
+	require.Equal("plain", entry["msg"])
+	require.NotContains(entry, "endEpoch")
+
+	require.NoError(Init(config.Log{Level: "debug", Format: FormatConsole}))
+	require.Error(Init(config.Log{Level: "verbose", Format: FormatConsole}))
+	require.Error(Init(config.Log{Level: "info", Format: "xml"}))
+}
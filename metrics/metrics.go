@@ -0,0 +1,103 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package metrics exposes the state and the progress of the distribution daemon to Prometheus. Amounts are in IOTX.
+package metrics
+
+import (
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// LastEndEpoch is the end epoch of the last committed distribution
+	LastEndEpoch = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "hermes",
+		Name:      "last_end_epoch",
+		Help:      "End epoch of the last committed distribution",
+	})
+	// ChainEpoch is the current epoch of the chain
+	ChainEpoch = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "hermes",
+		Name:      "chain_epoch",
+		Help:      "Current epoch of the chain",
+	})
+	// VaultBalance is the balance of the vault account
+	VaultBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "hermes",
+		Name:      "vault_balance_iotx",
+		Help:      "Balance of the vault account",
+	})
+	// UnclaimedBalance is the unclaimed reward of the vault account
+	UnclaimedBalance = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "hermes",
+		Name:      "unclaimed_balance_iotx",
+		Help:      "Unclaimed reward of the vault account",
+	})
+	// DistributedAmount is the amount distributed to the voters of a delegate, including the auto deposits
+	DistributedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hermes",
+		Name:      "distributed_amount_iotx_total",
+		Help:      "Amount distributed to the voters of a delegate",
+	}, []string{"delegate"})
+	// DropRecords is the number of drop records of a status
+	DropRecords = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "hermes",
+		Name:      "drop_records",
+		Help:      "Number of auto deposit drop records by status",
+	}, []string{"status"})
+	// ReceiptFailures is the number of actions which failed to be mined, by reason
+	ReceiptFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "hermes",
+		Name:      "receipt_failures_total",
+		Help:      "Number of sent actions which are reverted, dropped, not mined in time or whose receipt fails to be read",
+	}, []string{"reason"})
+	// PhaseDuration is the time spent in a phase of a distribution cycle
+	PhaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "hermes",
+		Name:      "cycle_phase_duration_seconds",
+		Help:      "Time spent in a phase of a distribution cycle",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+	}, []string{"phase"})
+
+	registry   = prometheus.NewRegistry()
+	rauPerIOTX = new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil))
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		LastEndEpoch,
+		ChainEpoch,
+		VaultBalance,
+		UnclaimedBalance,
+		DistributedAmount,
+		DropRecords,
+		ReceiptFailures,
+		PhaseDuration,
+	)
+}
+
+// IOTX returns the amount in Rau as IOTX
+func IOTX(rau *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(rau), rauPerIOTX).Float64()
+	return f
+}
+
+// ObservePhase records the time spent in phase since start
+func ObservePhase(phase string, start time.Time) {
+	PhaseDuration.WithLabelValues(phase).Observe(time.Since(start).Seconds())
+}
+
+// Handler returns the handler of the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"io/ioutil"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	rau, _ := new(big.Int).SetString("1500000000000000000", 10)
+	require.Equal(1.5, IOTX(rau))
+	require.Equal(float64(0), IOTX(big.NewInt(0)))
+
+	LastEndEpoch.Set(48)
+	VaultBalance.Set(IOTX(rau))
+	DistributedAmount.WithLabelValues("alpha").Add(2)
+	DropRecords.WithLabelValues("new").Set(3)
+	ObservePhase("claim", time.Now())
+
+	server := httptest.NewServer(Handler())
+	defer server.Close()
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(err)
+	for _, line := range []string{
+		"hermes_last_end_epoch 48",
+		"hermes_vault_balance_iotx 1.5",
+		`hermes_distributed_amount_iotx_total{delegate="alpha"} 2`,
+		`hermes_drop_records{status="new"} 3`,
+		`hermes_cycle_phase_duration_seconds_count{phase="claim"} 1`,
+		"go_goroutines",
+	} {
+		require.Contains(string(body), line)
+	}
+}
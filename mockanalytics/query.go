@@ -0,0 +1,227 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package mockanalytics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// field is a field of a selection set
+type field struct {
+	alias     string
+	name      string
+	arguments map[string]interface{}
+	selection []*field
+}
+
+// parser parses the subset of the GraphQL query language needed by the hermes query: an optional operation with
+// variable definitions, fields with aliases, arguments and nested selection sets. Fragments and directives are not
+// supported.
+type parser struct {
+	tokens    []string
+	pos       int
+	variables map[string]interface{}
+}
+
+// parseQuery returns the top level selection set of query with the variables resolved
+func parseQuery(query string, variables map[string]interface{}) ([]*field, error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, variables: variables}
+	if p.peek() == "query" {
+		p.next()
+		if p.peek() != "{" && p.peek() != "(" {
+			p.next()
+		}
+		if p.peek() == "(" {
+			// skip the variable definitions, the values come from variables
+			for p.peek() != ")" {
+				if p.peek() == "" {
+					return nil, fmt.Errorf("unterminated variable definitions")
+				}
+				p.next()
+			}
+			p.next()
+		}
+	}
+	selection, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != "" {
+		return nil, fmt.Errorf("unexpected %q after the query", p.peek())
+	}
+	return selection, nil
+}
+
+func (p *parser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *parser) expect(token string) error {
+	if got := p.next(); got != token {
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+func (p *parser) selectionSet() ([]*field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selection []*field
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		f, err := p.field()
+		if err != nil {
+			return nil, err
+		}
+		selection = append(selection, f)
+		if p.peek() == "," {
+			p.next()
+		}
+	}
+	p.next()
+	return selection, nil
+}
+
+func (p *parser) field() (*field, error) {
+	name := p.next()
+	if !isName(name) {
+		return nil, fmt.Errorf("expected field name, got %q", name)
+	}
+	f := &field{alias: name, name: name}
+	if p.peek() == ":" {
+		p.next()
+		f.name = p.next()
+		if !isName(f.name) {
+			return nil, fmt.Errorf("expected field name, got %q", f.name)
+		}
+	}
+	if p.peek() == "(" {
+		p.next()
+		f.arguments = make(map[string]interface{})
+		for p.peek() != ")" {
+			argument := p.next()
+			if !isName(argument) {
+				return nil, fmt.Errorf("expected argument name, got %q", argument)
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			f.arguments[argument] = value
+			if p.peek() == "," {
+				p.next()
+			}
+		}
+		p.next()
+	}
+	if p.peek() == "{" {
+		selection, err := p.selectionSet()
+		if err != nil {
+			return nil, err
+		}
+		f.selection = selection
+	}
+	return f, nil
+}
+
+func (p *parser) value() (interface{}, error) {
+	token := p.next()
+	switch {
+	case token == "$":
+		name := p.next()
+		value, ok := p.variables[name]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not defined", name)
+		}
+		return value, nil
+	case strings.HasPrefix(token, `"`):
+		return strconv.Unquote(token)
+	case token == "true" || token == "false":
+		return token == "true", nil
+	default:
+		n, err := strconv.ParseFloat(token, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported value %q", token)
+		}
+		return n, nil
+	}
+}
+
+func isName(token string) bool {
+	if token == "" {
+		return false
+	}
+	for i, r := range token {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+func tokenize(query string) ([]string, error) {
+	var tokens []string
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '#':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case strings.ContainsRune("{}():,$!=[]", r):
+			tokens = append(tokens, string(r))
+			i++
+		case r == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j + 1
+		case r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i + 1
+			for j < len(runes) && (runes[j] == '_' || runes[j] == '.' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package mockanalytics serves the hermes query of the analytics GraphQL API from fixture files, for tests and staging
+// runs which cannot reach the analytics endpoint.
+package mockanalytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// Fixture is the bookkeeping of every epoch served by the server
+	Fixture struct {
+		Epochs []*Epoch `json:"epochs"`
+	}
+
+	// Epoch is the bookkeeping of an epoch, of any reward address if RewardAddress is empty
+	Epoch struct {
+		Epoch         uint64      `json:"epoch"`
+		RewardAddress string      `json:"rewardAddress"`
+		Delegates     []*Delegate `json:"delegates"`
+	}
+
+	// Delegate is the rewards of the voters of a delegate in an epoch, amounts are decimal strings in Rau
+	Delegate struct {
+		DelegateName   string    `json:"delegateName"`
+		StakingAddress string    `json:"stakingAddress"`
+		Refund         string    `json:"refund"`
+		Rewards        []*Reward `json:"rewards"`
+	}
+
+	// Reward is the reward of a voter
+	Reward struct {
+		Voter  string `json:"voter"`
+		Amount string `json:"amount"`
+	}
+
+	// Server is an http.Handler serving the hermes query of fixture
+	Server struct {
+		fixture *Fixture
+	}
+
+	request struct {
+		Query     string                 `json:"query"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+
+	gqlError struct {
+		Message string `json:"message"`
+	}
+)
+
+// LoadFixture reads the JSON fixture file at path
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read fixture file")
+	}
+	fixture := &Fixture{}
+	if err := json.Unmarshal(data, fixture); err != nil {
+		return nil, errors.Wrap(err, "failed to parse fixture file")
+	}
+	for _, epoch := range fixture.Epochs {
+		for _, delegate := range epoch.Delegates {
+			if _, err := parseAmount(delegate.Refund); err != nil {
+				return nil, errors.Wrapf(err, "invalid refund of delegate %s in epoch %d", delegate.DelegateName, epoch.Epoch)
+			}
+			for _, reward := range delegate.Rewards {
+				if _, err := parseAmount(reward.Amount); err != nil {
+					return nil, errors.Wrapf(err, "invalid reward of voter %s in epoch %d", reward.Voter, epoch.Epoch)
+				}
+			}
+		}
+	}
+	return fixture, nil
+}
+
+// NewServer returns a server of fixture
+func NewServer(fixture *Fixture) *Server {
+	return &Server{fixture: fixture}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []gqlError{{Message: err.Error()}}})
+		return
+	}
+	data, err := s.Query(req.Query, req.Variables)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []gqlError{{Message: err.Error()}}})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+// Query returns the data of query, with only the selected fields
+func (s *Server) Query(query string, variables map[string]interface{}) (map[string]interface{}, error) {
+	selection, err := parseQuery(query, variables)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse query")
+	}
+	data := make(map[string]interface{})
+	for _, f := range selection {
+		if f.name != "hermes" {
+			return nil, fmt.Errorf("unknown field %s", f.name)
+		}
+		result, err := s.hermes(f.arguments)
+		if err != nil {
+			return nil, err
+		}
+		value, err := project(result, f.selection)
+		if err != nil {
+			return nil, err
+		}
+		data[f.alias] = value
+	}
+	return data, nil
+}
+
+// hermes aggregates the bookkeeping of the epochs in the window. The bookkeeping exists only if every epoch of the
+// window is in the fixture, and the service fee of a delegate is waived if it has at least waiverThreshold voters.
+func (s *Server) hermes(arguments map[string]interface{}) (map[string]interface{}, error) {
+	startEpoch, err := uintArgument(arguments, "startEpoch")
+	if err != nil {
+		return nil, err
+	}
+	epochCount, err := uintArgument(arguments, "epochCount")
+	if err != nil {
+		return nil, err
+	}
+	waiverThreshold, err := uintArgument(arguments, "waiverThreshold")
+	if err != nil {
+		return nil, err
+	}
+	rewardAddress, ok := arguments["rewardAddress"].(string)
+	if !ok {
+		return nil, errors.New("argument rewardAddress must be a string")
+	}
+
+	type delegateSum struct {
+		stakingAddress string
+		refund         *big.Int
+		rewards        map[string]*big.Int
+	}
+	var names []string
+	sums := make(map[string]*delegateSum)
+	for epoch := startEpoch; epoch < startEpoch+epochCount; epoch++ {
+		e := s.epoch(epoch, rewardAddress)
+		if e == nil {
+			return map[string]interface{}{"exist": false, "hermesDistribution": []interface{}{}}, nil
+		}
+		for _, d := range e.Delegates {
+			sum, ok := sums[d.DelegateName]
+			if !ok {
+				sum = &delegateSum{refund: big.NewInt(0), rewards: make(map[string]*big.Int)}
+				sums[d.DelegateName] = sum
+				names = append(names, d.DelegateName)
+			}
+			sum.stakingAddress = d.StakingAddress
+			refund, _ := parseAmount(d.Refund)
+			sum.refund.Add(sum.refund, refund)
+			for _, r := range d.Rewards {
+				amount, _ := parseAmount(r.Amount)
+				if _, ok := sum.rewards[r.Voter]; !ok {
+					sum.rewards[r.Voter] = big.NewInt(0)
+				}
+				sum.rewards[r.Voter].Add(sum.rewards[r.Voter], amount)
+			}
+		}
+	}
+
+	distributions := make([]interface{}, 0, len(names))
+	for _, name := range names {
+		sum := sums[name]
+		voters := make([]string, 0, len(sum.rewards))
+		for voter := range sum.rewards {
+			voters = append(voters, voter)
+		}
+		sort.Strings(voters)
+		rewards := make([]interface{}, 0, len(voters))
+		for _, voter := range voters {
+			rewards = append(rewards, map[string]interface{}{
+				"voterIotexAddress": voter,
+				"amount":            sum.rewards[voter].String(),
+			})
+		}
+		distributions = append(distributions, map[string]interface{}{
+			"delegateName":        name,
+			"stakingIotexAddress": sum.stakingAddress,
+			"voterCount":          len(voters),
+			"waiveServiceFee":     uint64(len(voters)) >= waiverThreshold,
+			"refund":              sum.refund.String(),
+			"rewardDistribution":  rewards,
+		})
+	}
+	return map[string]interface{}{"exist": true, "hermesDistribution": distributions}, nil
+}
+
+func (s *Server) epoch(epoch uint64, rewardAddress string) *Epoch {
+	for _, e := range s.fixture.Epochs {
+		if e.Epoch == epoch && (e.RewardAddress == "" || e.RewardAddress == rewardAddress) {
+			return e
+		}
+	}
+	return nil
+}
+
+// project returns the fields of value in selection, the GraphQL client rejects any field it does not select
+func project(value interface{}, selection []*field) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if selection == nil {
+			return nil, errors.New("object field must have a selection set")
+		}
+		result := make(map[string]interface{}, len(selection))
+		for _, f := range selection {
+			fieldValue, ok := v[f.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %s", f.name)
+			}
+			projected, err := project(fieldValue, f.selection)
+			if err != nil {
+				return nil, err
+			}
+			result[f.alias] = projected
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			projected, err := project(item, selection)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, projected)
+		}
+		return result, nil
+	default:
+		if selection != nil {
+			return nil, errors.New("scalar field must not have a selection set")
+		}
+		return v, nil
+	}
+}
+
+func uintArgument(arguments map[string]interface{}, name string) (uint64, error) {
+	switch v := arguments[name].(type) {
+	case float64:
+		if v < 0 || v != float64(uint64(v)) {
+			return 0, fmt.Errorf("argument %s must be a non-negative integer", name)
+		}
+		return uint64(v), nil
+	default:
+		return 0, fmt.Errorf("argument %s must be an integer", name)
+	}
+}
+
+func parseAmount(s string) (*big.Int, error) {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
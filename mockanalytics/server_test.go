@@ -0,0 +1,70 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package mockanalytics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	require := require.New(t)
+
+	s := NewServer(&Fixture{Epochs: []*Epoch{
+		{Epoch: 1, Delegates: []*Delegate{{DelegateName: "alpha", StakingAddress: "io1a", Refund: "10",
+			Rewards: []*Reward{{Voter: "io1b", Amount: "1"}}}}},
+		{Epoch: 2, Delegates: []*Delegate{{DelegateName: "alpha", StakingAddress: "io1a", Refund: "20",
+			Rewards: []*Reward{{Voter: "io1b", Amount: "2"}, {Voter: "io1a", Amount: "3"}}}}},
+	}})
+
+	query := `query($epochCount:Int!$startEpoch:Int!){
+		hermes(startEpoch: $startEpoch, epochCount: $epochCount, rewardAddress: "io1x", waiverThreshold: 3) {
+			exist
+			distributions: hermesDistribution { delegateName, refund, voterCount, waiveServiceFee,
+				rewardDistribution { voterIotexAddress, amount } }
+		}
+	}`
+	data, err := s.Query(query, map[string]interface{}{"startEpoch": float64(1), "epochCount": float64(2)})
+	require.NoError(err)
+	require.Equal(map[string]interface{}{
+		"hermes": map[string]interface{}{
+			"exist": true,
+			"distributions": []interface{}{map[string]interface{}{
+				"delegateName":    "alpha",
+				"refund":          "30",
+				"voterCount":      2,
+				"waiveServiceFee": false,
+				"rewardDistribution": []interface{}{
+					map[string]interface{}{"voterIotexAddress": "io1a", "amount": "3"},
+					map[string]interface{}{"voterIotexAddress": "io1b", "amount": "3"},
+				},
+			}},
+		},
+	}, data)
+
+	data, err = s.Query(query, map[string]interface{}{"startEpoch": float64(2), "epochCount": float64(2)})
+	require.NoError(err)
+	require.Equal(false, data["hermes"].(map[string]interface{})["exist"])
+
+	_, err = s.Query(`{ hermes(startEpoch: 1, epochCount: 1, rewardAddress: "io1x", waiverThreshold: 3) { unknown } }`, nil)
+	require.Error(err)
+	_, err = s.Query(`{ hermes(startEpoch: $missing, epochCount: 1, rewardAddress: "io1x", waiverThreshold: 3) { exist } }`, nil)
+	require.Error(err)
+}
+
+func TestLoadFixture(t *testing.T) {
+	require := require.New(t)
+
+	fixture, err := LoadFixture("testdata/fixture.json")
+	require.NoError(err)
+	require.Len(fixture.Epochs, 2)
+	require.Equal("alpha", fixture.Epochs[0].Delegates[0].DelegateName)
+
+	_, err = LoadFixture("testdata/missing.json")
+	require.Error(err)
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+// Package notify alerts the operators of the failures of a distribution through webhooks, Slack incoming webhooks
+// and email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iotexproject/iotex-hermes/config"
+	"github.com/iotexproject/iotex-hermes/logger"
+)
+
+// Event types
+const (
+	EventClaimFailed      = "claim_failed"
+	EventDistributeFailed = "distribute_failed"
+	EventCommitFailed     = "commit_failed"
+	EventDropRecordFailed = "drop_record_failed"
+	EventFeeSweepFailed   = "fee_sweep_failed"
+	EventLowBalance       = "low_balance"
+	EventPlanChanged      = "plan_changed"
+	EventRetryExhausted   = "retry_exhausted"
+)
+
+type (
+	// Event is a failure to alert, the zero fields are not relevant to the event
+	Event struct {
+		Type     string            `json:"type"`
+		Message  string            `json:"message"`
+		EndEpoch uint64            `json:"endEpoch,omitempty"`
+		Delegate string            `json:"delegate,omitempty"`
+		RecordID uint              `json:"recordID,omitempty"`
+		Fields   map[string]string `json:"fields,omitempty"`
+		Time     time.Time         `json:"time"`
+	}
+
+	// Notifier delivers an event to an alert channel
+	Notifier interface {
+		Notify(ctx context.Context, event *Event) error
+	}
+
+	webhook struct {
+		url string
+	}
+
+	slack struct {
+		url string
+	}
+
+	email struct {
+		cfg config.Email
+	}
+)
+
+var (
+	mu        sync.Mutex
+	notifiers []Notifier
+	timeout   = 10 * time.Second
+)
+
+// Init replaces the notifiers with the ones of cfg
+func Init(cfg config.Alerts) {
+	var ns []Notifier
+	for _, url := range cfg.Webhooks {
+		ns = append(ns, NewWebhook(url))
+	}
+	for _, url := range cfg.SlackWebhooks {
+		ns = append(ns, NewSlack(url))
+	}
+	if cfg.Email.SMTPAddress != "" {
+		ns = append(ns, NewEmail(cfg.Email))
+	}
+	SetNotifiers(ns...)
+	mu.Lock()
+	defer mu.Unlock()
+	timeout = cfg.Timeout
+}
+
+// SetNotifiers replaces the notifiers
+func SetNotifiers(ns ...Notifier) {
+	mu.Lock()
+	defer mu.Unlock()
+	notifiers = ns
+}
+
+// Send delivers event to every notifier concurrently, each within the timeout, logging the failures of delivery
+func Send(ctx context.Context, event *Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	mu.Lock()
+	ns, t := notifiers, timeout
+	mu.Unlock()
+	logger.Ctx(ctx).Warn("alert", zap.String("type", event.Type), zap.String("message", event.Message))
+	var wg sync.WaitGroup
+	for _, n := range ns {
+		wg.Add(1)
+		go func(n Notifier) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, t)
+			defer cancel()
+			if err := n.Notify(ctx, event); err != nil {
+				logger.Ctx(ctx).Error("failed to send alert", zap.String("type", event.Type), zap.Error(err))
+			}
+		}(n)
+	}
+	wg.Wait()
+}
+
+// String returns the event as a line of text
+func (e *Event) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[hermes] %s: %s", e.Type, e.Message)
+	if e.EndEpoch != 0 {
+		fmt.Fprintf(&b, ", end epoch %d", e.EndEpoch)
+	}
+	if e.Delegate != "" {
+		fmt.Fprintf(&b, ", delegate %s", e.Delegate)
+	}
+	if e.RecordID != 0 {
+		fmt.Fprintf(&b, ", record %d", e.RecordID)
+	}
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ", %s %s", k, e.Fields[k])
+	}
+	return b.String()
+}
+
+// NewWebhook returns a notifier posting the events as JSON to url
+func NewWebhook(url string) Notifier {
+	return &webhook{url: url}
+}
+
+func (w *webhook) Notify(ctx context.Context, event *Event) error {
+	return postJSON(ctx, w.url, event)
+}
+
+// NewSlack returns a notifier posting the events to the Slack compatible incoming webhook url
+func NewSlack(url string) Notifier {
+	return &slack{url: url}
+}
+
+func (s *slack) Notify(ctx context.Context, event *Event) error {
+	return postJSON(ctx, s.url, map[string]string{"text": event.String()})
+}
+
+// NewEmail returns a notifier mailing the events with the SMTP server of cfg
+func NewEmail(cfg config.Email) Notifier {
+	return &email{cfg: cfg}
+}
+
+func (e *email) Notify(ctx context.Context, event *Event) error {
+	host := e.cfg.SMTPAddress
+	if i := strings.LastIndex(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	to := strings.Split(e.cfg.To, ",")
+	for i := range to {
+		to[i] = strings.TrimSpace(to[i])
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: [hermes] %s\r\n\r\n%s\r\n",
+		e.cfg.From, strings.Join(to, ", "), event.Type, event.String())
+
+	// the connection is closed once ctx is done, so the session never outlives it
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.cfg.SMTPAddress)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+	if err := sendMail(conn, host, e.cfg, to, []byte(msg)); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// sendMail sends msg over conn as smtp.SendMail does
+func sendMail(conn net.Conn, host string, cfg config.Email, to []string, msg []byte) error {
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%s responds with status %d", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/iotexproject/iotex-hermes/config"
+)
+
+func TestSend(t *testing.T) {
+	require := require.New(t)
+
+	sink := NewSink()
+	webhook := httptest.NewServer(sink)
+	defer webhook.Close()
+	var text string
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		text = body["text"]
+	}))
+	defer slackServer.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	Init(config.Alerts{
+		Webhooks:      []string{failing.URL, webhook.URL},
+		SlackWebhooks: []string{slackServer.URL},
+		Timeout:       time.Second,
+	})
+	defer SetNotifiers()
+
+	// a failing notifier does not stop the others
+	Send(context.Background(), &Event{
+		Type:     EventDropRecordFailed,
+		Message:  "action is reverted",
+		EndEpoch: 24,
+		Delegate: "alpha",
+		RecordID: 3,
+		Fields:   map[string]string{"status": "error"},
+	})
+	events := sink.Events()
+	require.Len(events, 1)
+	require.Equal(EventDropRecordFailed, events[0].Type)
+	require.Equal(uint64(24), events[0].EndEpoch)
+	require.Equal("alpha", events[0].Delegate)
+	require.Equal(uint(3), events[0].RecordID)
+	require.Equal("error", events[0].Fields["status"])
+	require.False(events[0].Time.IsZero())
+	require.Equal("[hermes] drop_record_failed: action is reverted, end epoch 24, delegate alpha, record 3, status error",
+		text)
+}
+
+func TestSendTimeout(t *testing.T) {
+	require := require.New(t)
+
+	sink := NewSink()
+	webhook := httptest.NewServer(sink)
+	defer webhook.Close()
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	// an SMTP server which accepts the connection and never greets
+	smtpServer, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	defer smtpServer.Close()
+	go func() {
+		for {
+			conn, err := smtpServer.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	Init(config.Alerts{
+		Webhooks: []string{slow.URL, webhook.URL},
+		Email:    config.Email{SMTPAddress: smtpServer.Addr().String(), From: "hermes@iotex.io", To: "ops@iotex.io"},
+		Timeout:  200 * time.Millisecond,
+	})
+	defer SetNotifiers()
+
+	// every notifier has its own timeout, so the slow ones do not delay the others
+	start := time.Now()
+	Send(context.Background(), &Event{Type: EventLowBalance, Message: "low balance"})
+	require.Less(int64(time.Since(start)), int64(400*time.Millisecond))
+	require.Equal([]string{EventLowBalance}, sink.Types())
+
+	// the email notifier gives up on ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	n := NewEmail(config.Email{SMTPAddress: smtpServer.Addr().String(), From: "hermes@iotex.io", To: "ops@iotex.io"})
+	require.Equal(context.DeadlineExceeded, n.Notify(ctx, &Event{Type: EventLowBalance}))
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020 IoTeX
+// This is an alpha (internal) release and is not suitable for production. This source code is provided 'as is' and no
+// warranties are given as to title or non-infringement, merchantability or fitness for purpose and, to the extent
+// permitted by law, all liability for your use of the code is disclaimed. This source code is governed by Apache
+// License 2.0 that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Sink is an http.Handler recording the events posted by a webhook notifier, for tests and local runs
+type Sink struct {
+	mu     sync.Mutex
+	events []*Event
+}
+
+// NewSink returns an empty sink
+func NewSink() *Sink {
+	return &Sink{}
+}
+
+// ServeHTTP implements http.Handler
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	event := &Event{}
+	if err := json.NewDecoder(r.Body).Decode(event); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Events returns the events received so far
+func (s *Sink) Events() []*Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Event(nil), s.events...)
+}
+
+// Types returns the types of the events received so far
+func (s *Sink) Types() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	types := make([]string, 0, len(s.events))
+	for _, e := range s.events {
+		types = append(types, e.Type)
+	}
+	return types
+}
@@ -8,23 +8,12 @@ package util
 
 import (
 	"fmt"
-	"log"
-	"os"
 
 	"github.com/ethereum/go-ethereum/accounts/keystore"
 	"github.com/iotexproject/go-pkgs/crypto"
 	"github.com/iotexproject/iotex-antenna-go/v2/account"
 )
 
-// MustFetchNonEmptyParam must fetch a nonempty environment variable
-func MustFetchNonEmptyParam(key string) string {
-	str := os.Getenv(key)
-	if len(str) == 0 {
-		log.Fatalf("%s is not defined in env\n", key)
-	}
-	return str
-}
-
 // GetVaultAccount returns the vault account given the password
 func GetVaultAccount(pwd string) (account.Account, error) {
 	// load the keystore file